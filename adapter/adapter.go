@@ -4,6 +4,8 @@ package adapter
 import (
 	"context"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/kickplan/sdk-go/eval"
 )
@@ -22,6 +24,18 @@ type Adapter interface {
 	DecMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error
 }
 
+// Metric represents the current value of a metric for an evaluation context.
+type Metric struct {
+	Value     int64     `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrMetricNotFound is returned when a metric has no value for an evaluation context.
+var ErrMetricNotFound = fmt.Errorf("METRIC_NOT_FOUND")
+
+// ErrTypeMismatch is returned when a flag value does not match the requested type.
+var ErrTypeMismatch = fmt.Errorf("TYPE_MISMATCH")
+
 func genericResolve[T any](flag interface{}, defaultValue T) (T, error) {
 	if flag == nil {
 		return defaultValue, nil
@@ -31,5 +45,17 @@ func genericResolve[T any](flag interface{}, defaultValue T) (T, error) {
 		return v, nil
 	}
 
-	return defaultValue, fmt.Errorf("type assertion failed")
+	// JSON numbers are decoded as float64, accept them for int64 flags
+	// when they are whole numbers that int64 can represent.
+	if f, ok := flag.(float64); ok {
+		if _, ok := interface{}(defaultValue).(int64); ok {
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return defaultValue, fmt.Errorf("%w: %v is not an int64", ErrTypeMismatch, f)
+			}
+
+			return interface{}(int64(f)).(T), nil
+		}
+	}
+
+	return defaultValue, fmt.Errorf("%w: type assertion failed", ErrTypeMismatch)
 }
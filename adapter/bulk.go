@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// ErrBulkEvaluationUnsupported is returned when an adapter cannot evaluate
+// all flags at once.
+var ErrBulkEvaluationUnsupported = fmt.Errorf("BULK_EVALUATION_UNSUPPORTED")
+
+// FlagEvaluation is the outcome of the evaluation of a flag by a bulk evaluation.
+type FlagEvaluation struct {
+	Flag  string
+	Value interface{}
+	Details
+}
+
+// BulkEvaluator is implemented by adapters that can evaluate all flags for
+// an evaluation context at once.
+type BulkEvaluator interface {
+	// AllEvaluations returns the evaluations of all flags ordered by flag.
+	AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error)
+}
+
+// AllEvaluations evaluates all flags with a if it implements BulkEvaluator
+// and returns ErrBulkEvaluationUnsupported otherwise.
+func AllEvaluations(ctx context.Context, a Adapter, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	b, ok := a.(BulkEvaluator)
+	if !ok {
+		return nil, ErrBulkEvaluationUnsupported
+	}
+
+	return b.AllEvaluations(ctx, evalCtx)
+}
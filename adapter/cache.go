@@ -0,0 +1,273 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// DefaultCacheMaxEntries is the default maximum number of entries of a cache.
+const DefaultCacheMaxEntries = 10000
+
+// CacheOption is a function that configures a cache middleware.
+type CacheOption func(*cache)
+
+// WithCacheObserver sets a function that is called with every cache lookup,
+// e.g. kickplanprom.Collector.ObserveCache.
+func WithCacheObserver(fn func(hit bool)) CacheOption {
+	return func(c *cache) {
+		c.observe = fn
+	}
+}
+
+// WithCacheMaxEntries sets the maximum number of entries of the cache. Zero
+// or less disables the cache, every call goes to the wrapped adapter.
+// Defaults to DefaultCacheMaxEntries.
+func WithCacheMaxEntries(n int) CacheOption {
+	return func(c *cache) {
+		c.maxEntries = n
+	}
+}
+
+// WithCache returns a middleware that caches flag evaluations for ttl per
+// flag, type and evaluation context.
+//
+// Only successful evaluations whose details show that the flag was evaluated
+// are cached: evaluations that returned the default value or that the wrapped
+// adapter did not report a reason for are not. Values served from the cache are reported with ReasonCached and
+// the variant and adapter of the cached evaluation. Calls with
+// CallOptions.BypassCache skip the cache, calls with CallOptions.RequireFresh
+// refresh it. Setting a flag with SetBoolean invalidates its entries; metric
+// operations and bulk evaluations are not cached. Cached object values are
+// shared between evaluations and must not be modified.
+func WithCache(ttl time.Duration, opt ...CacheOption) Middleware {
+	return func(next Adapter) Adapter {
+		c := &cache{
+			Adapter:    next,
+			ttl:        ttl,
+			maxEntries: DefaultCacheMaxEntries,
+			entries:    make(map[string]cacheEntry),
+		}
+
+		for _, o := range opt {
+			o(c)
+		}
+
+		return c
+	}
+}
+
+// Verify that cache implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder and Wrapper.
+var (
+	_ Adapter        = (*cache)(nil)
+	_ BulkEvaluator  = (*cache)(nil)
+	_ MetricReader   = (*cache)(nil)
+	_ MetricRecorder = (*cache)(nil)
+	_ Wrapper        = (*cache)(nil)
+)
+
+type cacheEntry struct {
+	flag      string
+	value     interface{}
+	details   Details
+	expiresAt time.Time
+}
+
+type cache struct {
+	Adapter
+
+	ttl        time.Duration
+	maxEntries int
+	observe    func(hit bool)
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// Unwrap returns the wrapped adapter.
+func (c *cache) Unwrap() Adapter {
+	return c.Adapter
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (c *cache) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return cached(ctx, c, OperationBoolean, flag, defaultValue, evalCtx, Adapter.BooleanEvaluation)
+}
+
+// StringEvaluation returns the value of a string flag.
+func (c *cache) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return cached(ctx, c, OperationString, flag, defaultValue, evalCtx, Adapter.StringEvaluation)
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (c *cache) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return cached(ctx, c, OperationInt64, flag, defaultValue, evalCtx, Adapter.Int64Evaluation)
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (c *cache) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return cached(ctx, c, OperationObject, flag, defaultValue, evalCtx, Adapter.ObjectEvaluation)
+}
+
+// AllEvaluations evaluates all flags with the wrapped adapter.
+func (c *cache) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	return AllEvaluations(ctx, c.Adapter, evalCtx)
+}
+
+// RecordMetric records a value of a counter, gauge or histogram with the
+// wrapped adapter.
+func (c *cache) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return RecordMetric(ctx, c.Adapter, metric, kind, value, evalCtx)
+}
+
+// ReadMetric returns the value of a metric from the wrapped adapter.
+func (c *cache) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return ReadMetric(ctx, c.Adapter, metric, evalCtx)
+}
+
+// SetBoolean sets the value of a boolean flag and invalidates its entries.
+func (c *cache) SetBoolean(ctx context.Context, flag string, value bool) error {
+	err := c.Adapter.SetBoolean(ctx, flag, value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, e := range c.entries {
+		if e.flag == flag {
+			delete(c.entries, key)
+		}
+	}
+
+	return err
+}
+
+func (c *cache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+
+	return e, true
+}
+
+func (c *cache) put(key string, e cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+
+	if len(c.entries) < c.maxEntries {
+		c.entries[key] = e
+	}
+}
+
+// evict removes the expired entries or, if there are none, an arbitrary
+// entry. It must be called with c.mu held.
+func (c *cache) evict() {
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
+
+// cacheKey returns the key of the entry of an evaluation. It reports false
+// if the evaluation context cannot be encoded.
+func cacheKey(operation, flag string, evalCtx eval.Context) (string, bool) {
+	if len(evalCtx) == 0 {
+		return operation + "\x00" + flag + "\x00", true
+	}
+
+	b, err := json.Marshal(evalCtx)
+	if err != nil {
+		return "", false
+	}
+
+	return operation + "\x00" + flag + "\x00" + string(b), true
+}
+
+// cached returns the cached value of a flag or evaluates it with the
+// wrapped adapter and caches the result.
+func cached[T any](
+	ctx context.Context,
+	c *cache,
+	operation string,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	evaluate func(Adapter, context.Context, string, T, eval.Context) (T, error),
+) (T, error) {
+	o := CallOptionsFromContext(ctx)
+
+	key, cacheable := cacheKey(operation, flag, evalCtx)
+	cacheable = cacheable && !o.BypassCache && c.maxEntries > 0
+	if cacheable && !o.RequireFresh {
+		e, hit := c.get(key)
+		if c.observe != nil {
+			c.observe(hit)
+		}
+
+		if hit {
+			ReportDetails(ctx, Details{Variant: e.details.Variant, Reason: ReasonCached, Adapter: e.details.Adapter})
+			return e.value.(T), nil
+		}
+	}
+
+	var d Details
+	value, err := evaluate(c.Adapter, WithDetails(ctx, &d), flag, defaultValue, evalCtx)
+	ReportDetails(ctx, d)
+
+	if cacheable && err == nil && d.Reason != "" && d.Reason != ReasonDefault && d.Reason != ReasonError {
+		c.put(key, cacheEntry{flag: flag, value: value, details: d, expiresAt: time.Now().Add(c.ttl)})
+	}
+
+	return value, err
+}
@@ -0,0 +1,33 @@
+package adapter
+
+import "context"
+
+// CallOptions are per-call options that adapters and middlewares honor
+// where it makes sense. Like Details, they travel with the context of the
+// call.
+type CallOptions struct {
+	// BypassCache skips caches: the value is neither served from nor stored
+	// in them.
+	BypassCache bool
+
+	// RequireFresh skips cached values and asks the source of the flag for a
+	// fresh one, which replaces the cached value.
+	RequireFresh bool
+
+	// Tags annotate the call for telemetry, e.g. the feature or request that
+	// evaluates a flag.
+	Tags map[string]string
+}
+
+type callOptionsKey struct{}
+
+// WithCallOptions returns a copy of ctx that carries o to the adapters.
+func WithCallOptions(ctx context.Context, o CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey{}, o)
+}
+
+// CallOptionsFromContext returns the call options carried by ctx.
+func CallOptionsFromContext(ctx context.Context) CallOptions {
+	o, _ := ctx.Value(callOptionsKey{}).(CallOptions)
+	return o
+}
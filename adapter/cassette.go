@@ -0,0 +1,517 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Operations of adapters, recorded in cassettes and passed to interceptors.
+const (
+	OperationBoolean    = "boolean"
+	OperationString     = "string"
+	OperationInt64      = "int64"
+	OperationObject     = "object"
+	OperationSetBoolean = "set_boolean"
+	OperationSetMetric  = "set_metric"
+	OperationIncMetric  = "inc_metric"
+	OperationDecMetric  = "dec_metric"
+	OperationReadMetric = "read_metric"
+
+	OperationRecordMetric = "record_metric"
+
+	OperationAllEvaluations = "all_evaluations"
+)
+
+// ErrInteractionNotFound is returned by Replayer when a call was not recorded.
+var ErrInteractionNotFound = fmt.Errorf("INTERACTION_NOT_FOUND")
+
+// Verify that Recorder and Replayer implement Adapter, MetricReader and
+// MetricRecorder and that Recorder implements Wrapper and Lifecycle.
+var (
+	_ Adapter        = (*Recorder)(nil)
+	_ Adapter        = (*Replayer)(nil)
+	_ MetricReader   = (*Recorder)(nil)
+	_ MetricReader   = (*Replayer)(nil)
+	_ MetricRecorder = (*Recorder)(nil)
+	_ MetricRecorder = (*Replayer)(nil)
+	_ Wrapper        = (*Recorder)(nil)
+	_ Lifecycle      = (*Recorder)(nil)
+)
+
+// cassetteErrors are the errors restored by Replayer from their code, so
+// that they stay comparable with errors.Is.
+var cassetteErrors = []error{
+	ErrFlagNotFound,
+	ErrMetricNotFound,
+	ErrTypeMismatch,
+	ErrBulkEvaluationUnsupported,
+	ErrMetricReadUnsupported,
+	ErrMetricRecordUnsupported,
+	ErrNegativeCounter,
+	ErrInvalidMetricValue,
+	ErrMetricKindMismatch,
+	ErrQueueFull,
+}
+
+// Interaction is an adapter call recorded in a cassette.
+type Interaction struct {
+	Operation string          `json:"operation"`
+	Key       string          `json:"key"`
+	Context   eval.Context    `json:"context,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Details   Details         `json:"details"`
+
+	// Error is the message of the error of the call, ErrorCode the code of
+	// the error of this package it wraps and StatusCode the status code of
+	// the HTTPError it wraps.
+	Error      string `json:"error,omitempty"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// Cassette is a list of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an adapter that records every call to the wrapped adapter and
+// its result. Call Save, or Shutdown, to write the recorded interactions to
+// a cassette file that can be served by Replayer.
+type Recorder struct {
+	next Adapter
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a new Recorder that wraps next and saves to path.
+func NewRecorder(next Adapter, path string) *Recorder {
+	return &Recorder{
+		next: next,
+		path: path,
+	}
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.WriteFile(r.path, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Unwrap returns the wrapped adapter.
+func (r *Recorder) Unwrap() Adapter {
+	return r.next
+}
+
+// Init does nothing, the recorder is ready when it is created.
+func (r *Recorder) Init(_ context.Context) error {
+	return nil
+}
+
+// Shutdown saves the recorded interactions, see Save.
+func (r *Recorder) Shutdown(_ context.Context) error {
+	return r.Save()
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (r *Recorder) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return record(ctx, r, OperationBoolean, flag, evalCtx, func(ctx context.Context) (bool, error) {
+		return r.next.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// StringEvaluation returns the value of a string flag.
+func (r *Recorder) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return record(ctx, r, OperationString, flag, evalCtx, func(ctx context.Context) (string, error) {
+		return r.next.StringEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (r *Recorder) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return record(ctx, r, OperationInt64, flag, evalCtx, func(ctx context.Context) (int64, error) {
+		return r.next.Int64Evaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (r *Recorder) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return record(ctx, r, OperationObject, flag, evalCtx, func(ctx context.Context) (interface{}, error) {
+		return r.next.ObjectEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// SetBoolean sets the value of a boolean flag.
+func (r *Recorder) SetBoolean(ctx context.Context, flag string, value bool) error {
+	err := r.next.SetBoolean(ctx, flag, value)
+	r.add(OperationSetBoolean, flag, nil, value, Details{}, err)
+	return err
+}
+
+// SetMetric sets the value of a metric.
+func (r *Recorder) SetMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	err := r.next.SetMetric(ctx, metric, value, evalCtx)
+	r.add(OperationSetMetric, metric, evalCtx, value, Details{}, err)
+	return err
+}
+
+// IncMetric increments the value of a metric.
+func (r *Recorder) IncMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	err := r.next.IncMetric(ctx, metric, value, evalCtx)
+	r.add(OperationIncMetric, metric, evalCtx, value, Details{}, err)
+	return err
+}
+
+// DecMetric decrements the value of a metric.
+func (r *Recorder) DecMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	err := r.next.DecMetric(ctx, metric, value, evalCtx)
+	r.add(OperationDecMetric, metric, evalCtx, value, Details{}, err)
+	return err
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (r *Recorder) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	err := RecordMetric(ctx, r.next, metric, kind, value, evalCtx)
+	r.add(OperationRecordMetric, metric, evalCtx, value, Details{}, err)
+	return err
+}
+
+// ReadMetric returns the value of a metric.
+func (r *Recorder) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	m, err := ReadMetric(ctx, r.next, metric, evalCtx)
+	r.add(OperationReadMetric, metric, evalCtx, m, Details{}, err)
+	return m, err
+}
+
+func record[T any](
+	ctx context.Context,
+	r *Recorder,
+	operation string,
+	flag string,
+	evalCtx eval.Context,
+	evaluate func(context.Context) (T, error),
+) (T, error) {
+	var d Details
+	value, err := evaluate(WithDetails(ctx, &d))
+	ReportDetails(ctx, d)
+
+	r.add(operation, flag, evalCtx, value, d, err)
+	return value, err
+}
+
+func (r *Recorder) add(operation, key string, evalCtx eval.Context, value interface{}, d Details, err error) {
+	in := Interaction{
+		Operation: operation,
+		Key:       key,
+		Context:   evalCtx,
+		Details:   d,
+	}
+
+	if b, mErr := json.Marshal(value); mErr == nil {
+		in.Value = b
+	}
+
+	if err != nil {
+		in.Error = err.Error()
+
+		for _, e := range cassetteErrors {
+			if errors.Is(err, e) {
+				in.ErrorCode = e.Error()
+				break
+			}
+		}
+
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			in.StatusCode = httpErr.StatusCode
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+}
+
+// Replayer is an adapter that serves the interactions of a cassette written
+// by Recorder without calling any other adapter.
+//
+// Calls are matched by operation, flag or metric key and evaluation context.
+// Interactions recorded several times for the same call are served in order,
+// and the last one is repeated once all were served. Calls that failed when
+// they were recorded return the default value of the caller and the recorded
+// error, which keeps its message and wraps the errors of this package and
+// the HTTPError it wrapped.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+// NewReplayer returns a new Replayer that serves the cassette at path.
+func NewReplayer(path string) (*Replayer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(b, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette: %w", err)
+	}
+
+	r := &Replayer{
+		interactions: make(map[string][]Interaction),
+		served:       make(map[string]int),
+	}
+
+	for _, in := range cassette.Interactions {
+		key, err := interactionKey(in.Operation, in.Key, in.Context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode cassette: %w", err)
+		}
+		r.interactions[key] = append(r.interactions[key], in)
+	}
+
+	return r, nil
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (r *Replayer) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return replay(ctx, r, OperationBoolean, flag, defaultValue, evalCtx)
+}
+
+// StringEvaluation returns the value of a string flag.
+func (r *Replayer) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return replay(ctx, r, OperationString, flag, defaultValue, evalCtx)
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (r *Replayer) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return replay(ctx, r, OperationInt64, flag, defaultValue, evalCtx)
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (r *Replayer) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return replay(ctx, r, OperationObject, flag, defaultValue, evalCtx)
+}
+
+// SetBoolean replays setting the value of a boolean flag.
+func (r *Replayer) SetBoolean(_ context.Context, flag string, _ bool) error {
+	return r.replayUpdate(OperationSetBoolean, flag, nil)
+}
+
+// SetMetric replays setting the value of a metric.
+func (r *Replayer) SetMetric(_ context.Context, metric string, _ int64, evalCtx eval.Context) error {
+	return r.replayUpdate(OperationSetMetric, metric, evalCtx)
+}
+
+// IncMetric replays incrementing the value of a metric.
+func (r *Replayer) IncMetric(_ context.Context, metric string, _ int64, evalCtx eval.Context) error {
+	return r.replayUpdate(OperationIncMetric, metric, evalCtx)
+}
+
+// DecMetric replays decrementing the value of a metric.
+func (r *Replayer) DecMetric(_ context.Context, metric string, _ int64, evalCtx eval.Context) error {
+	return r.replayUpdate(OperationDecMetric, metric, evalCtx)
+}
+
+// RecordMetric replays recording a value of a counter, gauge or histogram.
+func (r *Replayer) RecordMetric(
+	_ context.Context,
+	metric string,
+	_ MetricKind,
+	_ float64,
+	evalCtx eval.Context,
+) error {
+	return r.replayUpdate(OperationRecordMetric, metric, evalCtx)
+}
+
+// ReadMetric replays reading the value of a metric.
+func (r *Replayer) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return replay(ctx, r, OperationReadMetric, metric, Metric{}, evalCtx)
+}
+
+func replay[T any](
+	ctx context.Context,
+	r *Replayer,
+	operation string,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+) (T, error) {
+	in, err := r.find(operation, flag, evalCtx)
+	if err != nil {
+		return defaultValue, err
+	}
+
+	ReportDetails(ctx, in.Details)
+
+	if err := in.err(); err != nil {
+		return defaultValue, err
+	}
+
+	value := defaultValue
+	if len(in.Value) > 0 {
+		if err := json.Unmarshal(in.Value, &value); err != nil {
+			return defaultValue, fmt.Errorf("failed to decode recorded value: %w", err)
+		}
+	}
+
+	return value, nil
+}
+
+func (r *Replayer) replayUpdate(operation, key string, evalCtx eval.Context) error {
+	in, err := r.find(operation, key, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	return in.err()
+}
+
+func (r *Replayer) find(operation, key string, evalCtx eval.Context) (Interaction, error) {
+	k, err := interactionKey(operation, key, evalCtx)
+	if err != nil {
+		return Interaction{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interactions := r.interactions[k]
+	if len(interactions) == 0 {
+		return Interaction{}, fmt.Errorf("%w: %s %q", ErrInteractionNotFound, operation, key)
+	}
+
+	i := r.served[k]
+	if i < len(interactions)-1 {
+		r.served[k] = i + 1
+	}
+
+	return interactions[i], nil
+}
+
+// err restores the recorded error with its message, keeping the errors of
+// this package comparable with errors.Is and HTTPError with errors.As.
+func (in Interaction) err() error {
+	if in.Error == "" {
+		return nil
+	}
+
+	var cause error
+	if in.StatusCode != 0 {
+		cause = &HTTPError{StatusCode: in.StatusCode}
+	}
+
+	for _, e := range cassetteErrors {
+		code := e.Error()
+
+		// cassettes recorded without error codes only have the message
+		if in.ErrorCode == code ||
+			in.ErrorCode == "" && (in.Error == code || strings.HasPrefix(in.Error, code+":")) {
+			cause = e
+			break
+		}
+	}
+
+	switch {
+	case cause == nil:
+		return errors.New(in.Error)
+	case cause.Error() == in.Error:
+		return cause
+	default:
+		return &recordedError{message: in.Error, cause: cause}
+	}
+}
+
+// recordedError is an error restored from a cassette. It has the recorded
+// message and wraps the error it was recorded with.
+type recordedError struct {
+	message string
+	cause   error
+}
+
+// Error returns the recorded message.
+func (e *recordedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the error the message was recorded with.
+func (e *recordedError) Unwrap() error {
+	return e.cause
+}
+
+func interactionKey(operation, key string, evalCtx eval.Context) (string, error) {
+	c, err := encodeContext(evalCtx)
+	if err != nil {
+		return "", err
+	}
+
+	return operation + "\x00" + key + "\x00" + c, nil
+}
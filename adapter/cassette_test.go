@@ -0,0 +1,148 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	inMemory := NewInMemory()
+	inMemory.Flags["enabled"] = InMemoryFlag{Value: true}
+	inMemory.Flags["limit"] = InMemoryFlag{Value: int64(10)}
+	inMemory.Flags["plan"] = InMemoryFlag{Value: map[string]interface{}{"name": "pro"}}
+
+	evalCtx := eval.Context{"account_id": "account", "seats": 5}
+
+	recorder := NewRecorder(inMemory, path)
+	if _, err := recorder.BooleanEvaluation(context.TODO(), "enabled", false, evalCtx); err != nil {
+		t.Fatalf("failed to record flag: %v", err)
+	}
+
+	if _, err := recorder.Int64Evaluation(context.TODO(), "limit", 0, evalCtx); err != nil {
+		t.Fatalf("failed to record flag: %v", err)
+	}
+
+	if _, err := recorder.ObjectEvaluation(context.TODO(), "plan", nil, evalCtx); err != nil {
+		t.Fatalf("failed to record flag: %v", err)
+	}
+
+	if _, err := recorder.StringEvaluation(context.TODO(), "enabled", "", evalCtx); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected error to be ErrTypeMismatch, got %v", err)
+	}
+
+	if err := recorder.IncMetric(context.TODO(), "seats", 1, evalCtx); err != nil {
+		t.Fatalf("failed to record metric: %v", err)
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+
+	var d Details
+	b, err := replayer.BooleanEvaluation(WithDetails(context.TODO(), &d), "enabled", false, evalCtx)
+	if err != nil {
+		t.Fatalf("failed to replay flag: %v", err)
+	}
+
+	if b != true {
+		t.Fatalf("expected replayed flag to be true")
+	}
+
+	if d.Reason != ReasonStatic {
+		t.Fatalf("expected replayed reason to be %s, got %s", ReasonStatic, d.Reason)
+	}
+
+	i, err := replayer.Int64Evaluation(context.TODO(), "limit", 0, evalCtx)
+	if err != nil {
+		t.Fatalf("failed to replay flag: %v", err)
+	}
+
+	if i != 10 {
+		t.Fatalf("expected replayed flag to be 10, got %d", i)
+	}
+
+	obj, err := replayer.ObjectEvaluation(context.TODO(), "plan", nil, evalCtx)
+	if err != nil {
+		t.Fatalf("failed to replay flag: %v", err)
+	}
+
+	if m, ok := obj.(map[string]interface{}); !ok || m["name"] != "pro" {
+		t.Fatalf("expected replayed object to have name pro, got %v", obj)
+	}
+
+	if _, err := replayer.StringEvaluation(context.TODO(), "enabled", "", evalCtx); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected error to be ErrTypeMismatch, got %v", err)
+	}
+
+	if err := replayer.IncMetric(context.TODO(), "seats", 1, evalCtx); err != nil {
+		t.Fatalf("failed to replay metric: %v", err)
+	}
+
+	_, err = replayer.BooleanEvaluation(context.TODO(), "enabled", false, eval.Context{"account_id": "other"})
+	if !errors.Is(err, ErrInteractionNotFound) {
+		t.Fatalf("expected error to be ErrInteractionNotFound, got %v", err)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	failing := Chain(NewInMemory(), Intercept(func(_ context.Context, call *Call, _ func(context.Context) error) error {
+		switch call.Key {
+		case "missing":
+			return fmt.Errorf("%w: flag %q", ErrFlagNotFound, call.Key)
+		case "unavailable":
+			return &HTTPError{StatusCode: http.StatusServiceUnavailable}
+		default:
+			return errors.New("UNKNOWN_ERROR")
+		}
+	}))
+
+	recorder := NewRecorder(failing, path)
+	for _, flag := range []string{"missing", "unavailable", "unknown"} {
+		if _, err := recorder.StringEvaluation(context.TODO(), flag, "recorded", nil); err == nil {
+			t.Fatalf("expected error for flag %s, got nil", flag)
+		}
+	}
+
+	if err := Shutdown(context.TODO(), recorder); err != nil {
+		t.Fatalf("failed to shut down recorder: %v", err)
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+
+	s, err := replayer.StringEvaluation(context.TODO(), "missing", "replayed", nil)
+	if !errors.Is(err, ErrFlagNotFound) || err.Error() != `FLAG_NOT_FOUND: flag "missing"` {
+		t.Fatalf("expected error to be ErrFlagNotFound with the recorded message, got %v", err)
+	}
+
+	if s != "replayed" {
+		t.Fatalf("expected the default value of the caller, got %q", s)
+	}
+
+	var httpErr *HTTPError
+	if _, err := replayer.StringEvaluation(context.TODO(), "unavailable", "", nil); !errors.As(err, &httpErr) ||
+		httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected error to be an HTTPError with status 503, got %v", err)
+	}
+
+	if _, err := replayer.StringEvaluation(context.TODO(), "unknown", "", nil); err == nil || err.Error() != "UNKNOWN_ERROR" {
+		t.Fatalf("expected error UNKNOWN_ERROR, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package adapter
+
+import "context"
+
+// Reasons reported in evaluation details.
+const (
+	// ReasonStatic is reported when a flag has a static value.
+	ReasonStatic = "STATIC"
+
+	// ReasonDefault is reported when the default value was returned.
+	ReasonDefault = "DEFAULT"
+
+	// ReasonTargetingMatch is reported when a value was resolved by targeting rules.
+	ReasonTargetingMatch = "TARGETING_MATCH"
+
+	// ReasonError is reported when the evaluation failed.
+	ReasonError = "ERROR"
+
+	// ReasonCached is reported when a value was served from a cache.
+	ReasonCached = "CACHED"
+)
+
+// Details describes how an adapter resolved a flag.
+type Details struct {
+	Variant   string `json:"variant,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// Adapter names the adapter that resolved the flag when adapters are
+	// composed, e.g. with Fallback.
+	Adapter string `json:"adapter,omitempty"`
+}
+
+type detailsKey struct{}
+
+// WithDetails returns a copy of ctx that collects the details reported by
+// adapters evaluating a flag with it into d.
+//
+// Adapters that evaluate several flags with the same context concurrently
+// must give every evaluation its own context.
+func WithDetails(ctx context.Context, d *Details) context.Context {
+	return context.WithValue(ctx, detailsKey{}, d)
+}
+
+// ReportDetails reports the details of the evaluation running with ctx.
+// It is a no-op unless the details were requested with WithDetails. Empty
+// fields of d do not overwrite fields reported before.
+func ReportDetails(ctx context.Context, d Details) {
+	dst, ok := ctx.Value(detailsKey{}).(*Details)
+	if !ok || dst == nil {
+		return
+	}
+
+	if d.Variant != "" {
+		dst.Variant = d.Variant
+	}
+
+	if d.Reason != "" {
+		dst.Reason = d.Reason
+	}
+
+	if d.ErrorCode != "" {
+		dst.ErrorCode = d.ErrorCode
+	}
+
+	if d.Adapter != "" {
+		dst.Adapter = d.Adapter
+	}
+}
@@ -0,0 +1,384 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// FallbackPolicy decides when Fallback tries the next adapter of its chain.
+type FallbackPolicy int
+
+const (
+	// FallThroughOnError tries the next adapter when a flag is not found or
+	// the evaluation fails with any error.
+	FallThroughOnError FallbackPolicy = iota
+
+	// FallThroughOnNotFound tries the next adapter only when a flag is not
+	// found. Other errors are returned.
+	FallThroughOnNotFound
+)
+
+// Verify that Fallback implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder, StatusReporter and Lifecycle.
+var (
+	_ Adapter        = (*Fallback)(nil)
+	_ BulkEvaluator  = (*Fallback)(nil)
+	_ MetricReader   = (*Fallback)(nil)
+	_ MetricRecorder = (*Fallback)(nil)
+	_ StatusReporter = (*Fallback)(nil)
+	_ Lifecycle      = (*Fallback)(nil)
+)
+
+// FallbackLink is an adapter of a Fallback chain.
+type FallbackLink struct {
+	// Name is reported as the adapter in evaluation details. It defaults to
+	// the type of the adapter.
+	Name string
+
+	Adapter Adapter
+	Policy  FallbackPolicy
+
+	// FallThroughWrites makes metric updates and SetBoolean calls that fail
+	// with this adapter try the next adapter. By default writes are not
+	// applied to the rest of the chain, so that e.g. usage that the Kickplan
+	// API failed to receive is reported as an error instead of being kept
+	// by an in-memory adapter.
+	FallThroughWrites bool
+
+	// IgnoreStatus leaves the adapter out of the status of the chain, e.g.
+	// in-memory defaults that are always ready, so that the chain is only
+	// ready once the adapters that load flags are.
+	IgnoreStatus bool
+}
+
+// Fallback is an adapter that evaluates flags with a chain of adapters, e.g.
+// the Kickplan API, then a snapshot file, then in-memory defaults.
+//
+// A flag is not found when an adapter returns ErrFlagNotFound or reports
+// ReasonDefault. The policy of each link decides whether the next adapter
+// is tried; the last adapter's result is returned as is. The name of the
+// adapter that produced the value is reported in Details.Adapter.
+//
+// Metric reads follow the same chain, where ErrMetricNotFound and
+// ErrMetricReadUnsupported count as not found. Metric updates and SetBoolean
+// are sent to the first adapter; they only try the next adapter when they
+// fail with an adapter whose link has FallThroughWrites set.
+type Fallback struct {
+	links []FallbackLink
+}
+
+// NewFallback returns a new Fallback adapter that tries the links in order.
+func NewFallback(links ...FallbackLink) *Fallback {
+	if len(links) == 0 {
+		panic("adapter: fallback chain without adapters")
+	}
+
+	f := &Fallback{links: make([]FallbackLink, len(links))}
+	for i, l := range links {
+		if l.Name == "" {
+			l.Name = fmt.Sprintf("%T", l.Adapter)
+		}
+		f.links[i] = l
+	}
+
+	return f
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (f *Fallback) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return fallback(ctx, f, flag, defaultValue, evalCtx, Adapter.BooleanEvaluation)
+}
+
+// StringEvaluation returns the value of a string flag.
+func (f *Fallback) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return fallback(ctx, f, flag, defaultValue, evalCtx, Adapter.StringEvaluation)
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (f *Fallback) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return fallback(ctx, f, flag, defaultValue, evalCtx, Adapter.Int64Evaluation)
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (f *Fallback) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return fallback(ctx, f, flag, defaultValue, evalCtx, Adapter.ObjectEvaluation)
+}
+
+// AllEvaluations merges the evaluations of all adapters of the chain that
+// can evaluate all flags at once. Flags are taken from the first adapter
+// that resolves them.
+func (f *Fallback) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	resolved := make(map[string]FlagEvaluation)
+	supported := false
+	for i, l := range f.links {
+		evaluations, err := AllEvaluations(ctx, l.Adapter, evalCtx)
+		if errors.Is(err, ErrBulkEvaluationUnsupported) {
+			continue
+		}
+		supported = true
+
+		if err != nil {
+			if l.Policy == FallThroughOnError && i < len(f.links)-1 {
+				continue
+			}
+
+			return nil, err
+		}
+
+		for _, e := range evaluations {
+			if _, ok := resolved[e.Flag]; ok || e.ErrorCode != "" {
+				continue
+			}
+
+			e.Adapter = l.Name
+			resolved[e.Flag] = e
+		}
+	}
+
+	if !supported {
+		return nil, ErrBulkEvaluationUnsupported
+	}
+
+	evaluations := make([]FlagEvaluation, 0, len(resolved))
+	for _, e := range resolved {
+		evaluations = append(evaluations, e)
+	}
+
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Flag < evaluations[j].Flag
+	})
+
+	return evaluations, nil
+}
+
+// Init initializes the adapters of the chain in order. It stops at the
+// first adapter that fails.
+func (f *Fallback) Init(ctx context.Context) error {
+	for _, l := range f.links {
+		if err := Init(ctx, l.Adapter); err != nil {
+			return fmt.Errorf("failed to initialize %s: %w", l.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown shuts down all adapters of the chain.
+func (f *Fallback) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, l := range f.links {
+		if err := Shutdown(ctx, l.Adapter); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down %s: %w", l.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Status returns the best status of the adapters of the chain that do not
+// have IgnoreStatus set: ready if any adapter is ready, else stale if any
+// adapter is stale, else error if any adapter failed, and not ready
+// otherwise. A chain whose adapters all ignore their status is ready.
+func (f *Fallback) Status() (Status, error) {
+	status, err, counted := StatusNotReady, error(nil), false
+	for _, l := range f.links {
+		if l.IgnoreStatus {
+			continue
+		}
+		counted = true
+
+		s, e := StatusOf(l.Adapter)
+		switch {
+		case s == StatusReady:
+			return s, nil
+		case s == StatusStale && status != StatusStale,
+			s == StatusError && status == StatusNotReady:
+			status, err = s, e
+		}
+	}
+
+	if !counted {
+		return StatusReady, nil
+	}
+
+	return status, err
+}
+
+// WatchStatus calls fn with every change of the status of the chain until
+// stop is called.
+func (f *Fallback) WatchStatus(fn func(StatusEvent)) (stop func()) {
+	var mu sync.Mutex
+	last, _ := f.Status()
+
+	watch := func(StatusEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		s, err := f.Status()
+		if s == last && err == nil {
+			return
+		}
+		last = s
+
+		fn(StatusEvent{Status: s, Err: err})
+	}
+
+	stops := make([]func(), 0, len(f.links))
+	for _, l := range f.links {
+		if !l.IgnoreStatus {
+			stops = append(stops, WatchStatus(l.Adapter, watch))
+		}
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// SetBoolean sets the value of a boolean flag.
+func (f *Fallback) SetBoolean(ctx context.Context, flag string, value bool) error {
+	return f.write(func(a Adapter) error {
+		return a.SetBoolean(ctx, flag, value)
+	})
+}
+
+// SetMetric sets the value of a metric.
+func (f *Fallback) SetMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.write(func(a Adapter) error {
+		return a.SetMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// IncMetric increments the value of a metric.
+func (f *Fallback) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.write(func(a Adapter) error {
+		return a.IncMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// DecMetric decrements the value of a metric.
+func (f *Fallback) DecMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.write(func(a Adapter) error {
+		return a.DecMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (f *Fallback) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return f.write(func(a Adapter) error {
+		return RecordMetric(ctx, a, metric, kind, value, evalCtx)
+	})
+}
+
+// ReadMetric returns the value of a metric.
+func (f *Fallback) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	var (
+		m   Metric
+		err error
+	)
+	for i, l := range f.links {
+		m, err = ReadMetric(ctx, l.Adapter, metric, evalCtx)
+		if err == nil || i == len(f.links)-1 {
+			break
+		}
+
+		notFound := errors.Is(err, ErrMetricNotFound) || errors.Is(err, ErrMetricReadUnsupported)
+		if l.Policy == FallThroughOnNotFound && !notFound {
+			break
+		}
+	}
+
+	return m, err
+}
+
+// write calls fn with the adapters of the chain until it succeeds or an
+// adapter without FallThroughWrites fails.
+func (f *Fallback) write(fn func(Adapter) error) error {
+	var err error
+	for _, l := range f.links {
+		if err = fn(l.Adapter); err == nil || !l.FallThroughWrites {
+			break
+		}
+	}
+
+	return err
+}
+
+// fallback evaluates a flag with the adapters of the chain until one
+// resolves it or the policy of an adapter stops the chain.
+func fallback[T any](
+	ctx context.Context,
+	f *Fallback,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	evaluate func(Adapter, context.Context, string, T, eval.Context) (T, error),
+) (T, error) {
+	for i, l := range f.links {
+		// every adapter reports into its own details, so that only the
+		// details of the adapter whose result is returned are reported
+		var d Details
+		value, err := evaluate(l.Adapter, WithDetails(ctx, &d), flag, defaultValue, evalCtx)
+
+		notFound := errors.Is(err, ErrFlagNotFound) || (err == nil && d.Reason == ReasonDefault)
+		last := i == len(f.links)-1
+		fallThrough := notFound || (err != nil && l.Policy == FallThroughOnError)
+
+		if last || !fallThrough {
+			d.Adapter = l.Name
+			ReportDetails(ctx, d)
+			return value, err
+		}
+	}
+
+	// unreachable, NewFallback requires at least one link
+	return defaultValue, ErrFlagNotFound
+}
@@ -0,0 +1,159 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// unavailableAdapter is an adapter whose boolean evaluations fail.
+type unavailableAdapter struct {
+	*InMemory
+}
+
+func (a *unavailableAdapter) BooleanEvaluation(
+	_ context.Context,
+	_ string,
+	defaultValue bool,
+	_ eval.Context,
+) (bool, error) {
+	return defaultValue, errUnavailable
+}
+
+func TestFallback(t *testing.T) {
+	primary := NewInMemory()
+	primary.Flags["a"] = InMemoryFlag{Value: true}
+
+	defaults := NewInMemory()
+	defaults.Flags["a"] = InMemoryFlag{Value: false}
+	defaults.Flags["b"] = InMemoryFlag{Value: true}
+
+	f := NewFallback(
+		FallbackLink{Name: "primary", Adapter: primary, Policy: FallThroughOnNotFound},
+		FallbackLink{Name: "defaults", Adapter: defaults},
+	)
+
+	for flag, expected := range map[string]struct {
+		value   bool
+		adapter string
+		reason  string
+	}{
+		"a": {true, "primary", ReasonStatic},
+		"b": {true, "defaults", ReasonStatic},
+		"c": {false, "defaults", ReasonDefault},
+	} {
+		var d Details
+		v, err := f.BooleanEvaluation(WithDetails(context.TODO(), &d), flag, false, nil)
+		if err != nil {
+			t.Fatalf("failed to evaluate flag %s: %v", flag, err)
+		}
+
+		if v != expected.value || d.Adapter != expected.adapter || d.Reason != expected.reason {
+			t.Fatalf("expected flag %s to be %v from %s with reason %s, got %v from %s with reason %s",
+				flag, expected.value, expected.adapter, expected.reason, v, d.Adapter, d.Reason)
+		}
+	}
+
+	all, err := f.AllEvaluations(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("failed to evaluate all flags: %v", err)
+	}
+
+	if len(all) != 2 || all[0].Value != true || all[0].Adapter != "primary" || all[1].Adapter != "defaults" {
+		t.Fatalf("unexpected evaluations %+v", all)
+	}
+}
+
+func TestFallbackPolicy(t *testing.T) {
+	defaults := NewInMemory()
+	defaults.Flags["a"] = InMemoryFlag{Value: true}
+
+	f := NewFallback(
+		FallbackLink{Name: "primary", Adapter: &unavailableAdapter{NewInMemory()}, Policy: FallThroughOnError},
+		FallbackLink{Name: "defaults", Adapter: defaults},
+	)
+
+	var d Details
+	v, err := f.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, nil)
+	if err != nil || !v || d.Adapter != "defaults" {
+		t.Fatalf("expected flag a to be true from defaults, got %v from %s, %v", v, d.Adapter, err)
+	}
+
+	f = NewFallback(
+		FallbackLink{Name: "primary", Adapter: &unavailableAdapter{NewInMemory()}, Policy: FallThroughOnNotFound},
+		FallbackLink{Name: "defaults", Adapter: defaults},
+	)
+
+	d = Details{}
+	_, err = f.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, nil)
+	if !errors.Is(err, errUnavailable) || d.Adapter != "primary" {
+		t.Fatalf("expected error %v from primary, got %v from %s", errUnavailable, err, d.Adapter)
+	}
+}
+
+func TestFallbackMetrics(t *testing.T) {
+	primary, secondary := NewInMemory(), NewInMemory()
+	f := NewFallback(
+		FallbackLink{Adapter: primary, Policy: FallThroughOnNotFound},
+		FallbackLink{Adapter: secondary},
+	)
+
+	if err := secondary.SetMetric(context.TODO(), "seats", 3, nil); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	m, err := f.ReadMetric(context.TODO(), "seats", nil)
+	if err != nil || m.Value != 3 {
+		t.Fatalf("expected metric seats to be 3, got %d, %v", m.Value, err)
+	}
+
+	if err := f.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if v := primary.GetMetric("seats", nil); v != 1 {
+		t.Fatalf("expected metric seats of primary to be 1, got %d", v)
+	}
+}
+
+// unavailableWriter is an adapter whose metric updates fail.
+type unavailableWriter struct {
+	*InMemory
+}
+
+func (a *unavailableWriter) IncMetric(context.Context, string, int64, eval.Context) error {
+	return errUnavailable
+}
+
+func TestFallbackWrites(t *testing.T) {
+	primary, secondary := &unavailableWriter{InMemory: NewInMemory()}, NewInMemory()
+	f := NewFallback(
+		FallbackLink{Adapter: primary, Policy: FallThroughOnError},
+		FallbackLink{Adapter: secondary},
+	)
+
+	if err := f.IncMetric(context.TODO(), "seats", 1, nil); !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected error %v, got %v", errUnavailable, err)
+	}
+
+	if v := secondary.GetMetric("seats", nil); v != 0 {
+		t.Fatalf("expected failed update not to reach the secondary adapter, got %d", v)
+	}
+
+	f = NewFallback(
+		FallbackLink{Adapter: primary, Policy: FallThroughOnError, FallThroughWrites: true},
+		FallbackLink{Adapter: secondary},
+	)
+
+	if err := f.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if v := secondary.GetMetric("seats", nil); v != 1 {
+		t.Fatalf("expected metric seats of secondary to be 1, got %d", v)
+	}
+}
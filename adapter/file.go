@@ -0,0 +1,314 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Verify that File implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder, StatusReporter and Lifecycle.
+var (
+	_ Adapter        = (*File)(nil)
+	_ BulkEvaluator  = (*File)(nil)
+	_ MetricReader   = (*File)(nil)
+	_ MetricRecorder = (*File)(nil)
+	_ StatusReporter = (*File)(nil)
+	_ Lifecycle      = (*File)(nil)
+)
+
+// File is an adapter that serves flags from a snapshot file, e.g. one
+// exported from production with the kickplan command. Metrics are kept in
+// memory.
+//
+// A flag is resolved from the most specific snapshot entry that has it and
+// whose context is a subset of the evaluation context. Flags that are not in
+// any matching entry are not found.
+//
+// The snapshot is loaded by Init. Until then the adapter is not ready and no
+// flag is found.
+type File struct {
+	path string
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+
+	metrics *InMemory
+	status  StatusTracker
+}
+
+// NewFile returns a new File adapter that serves the snapshot stored at path
+// in JSON or YAML format, see SnapshotFormatFromPath.
+func NewFile(path string) *File {
+	return &File{
+		path:    path,
+		metrics: NewInMemory(),
+	}
+}
+
+// Init loads the snapshot file. If it fails, the status of the adapter
+// becomes error.
+func (f *File) Init(_ context.Context) error {
+	return f.Reload()
+}
+
+// Shutdown does nothing, the snapshot file is not kept open.
+func (f *File) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// Reload reads the snapshot file again. If it fails, the adapter keeps
+// serving the previous snapshot and its status becomes stale, or error if no
+// snapshot was loaded yet.
+func (f *File) Reload() error {
+	if err := f.load(); err != nil {
+		s := StatusError
+		if current, _ := f.status.Status(); current.Serving() {
+			s = StatusStale
+		}
+		f.status.SetStatus(s, err)
+
+		return err
+	}
+
+	f.status.SetStatus(StatusReady, nil)
+	return nil
+}
+
+// Status returns the status of the adapter.
+func (f *File) Status() (Status, error) {
+	return f.status.Status()
+}
+
+// WatchStatus calls fn with every change of the status until stop is called.
+func (f *File) WatchStatus(fn func(StatusEvent)) (stop func()) {
+	return f.status.WatchStatus(fn)
+}
+
+func (f *File) load() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	s, err := ReadSnapshot(file, SnapshotFormatFromPath(f.path))
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.snapshot = s
+	f.mu.Unlock()
+
+	return nil
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (f *File) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	value, ok := f.find(ctx, flag, evalCtx)
+	if !ok {
+		return defaultValue, ErrFlagNotFound
+	}
+
+	return genericResolve[bool](value, defaultValue)
+}
+
+// StringEvaluation returns the value of a string flag.
+func (f *File) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	value, ok := f.find(ctx, flag, evalCtx)
+	if !ok {
+		return defaultValue, ErrFlagNotFound
+	}
+
+	return genericResolve[string](value, defaultValue)
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (f *File) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	value, ok := f.find(ctx, flag, evalCtx)
+	if !ok {
+		return defaultValue, ErrFlagNotFound
+	}
+
+	return genericResolve[int64](value, defaultValue)
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (f *File) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	value, ok := f.find(ctx, flag, evalCtx)
+	if !ok {
+		return defaultValue, ErrFlagNotFound
+	}
+
+	return value, nil
+}
+
+// AllEvaluations returns the values of all flags for an evaluation context.
+func (f *File) AllEvaluations(_ context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	resolved := make(map[string]FlagEvaluation)
+	for _, e := range f.matching(evalCtx) {
+		for flag, sf := range e.Flags {
+			if _, ok := resolved[flag]; ok {
+				continue
+			}
+
+			resolved[flag] = FlagEvaluation{
+				Flag:    flag,
+				Value:   sf.Value,
+				Details: snapshotDetails(e, sf),
+			}
+		}
+	}
+
+	evaluations := make([]FlagEvaluation, 0, len(resolved))
+	for _, e := range resolved {
+		evaluations = append(evaluations, e)
+	}
+
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Flag < evaluations[j].Flag
+	})
+
+	return evaluations, nil
+}
+
+// SetBoolean sets the value of a boolean flag.
+func (f *File) SetBoolean(_ context.Context, _ string, _ bool) error {
+	return fmt.Errorf("not implemented")
+}
+
+// SetMetric sets the value of a metric.
+func (f *File) SetMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.metrics.SetMetric(ctx, metric, value, evalCtx)
+}
+
+// IncMetric increments the value of a metric.
+func (f *File) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.metrics.IncMetric(ctx, metric, value, evalCtx)
+}
+
+// DecMetric decrements the value of a metric.
+func (f *File) DecMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return f.metrics.DecMetric(ctx, metric, value, evalCtx)
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (f *File) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return f.metrics.RecordMetric(ctx, metric, kind, value, evalCtx)
+}
+
+// ReadMetric returns the value of a metric.
+func (f *File) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return f.metrics.ReadMetric(ctx, metric, evalCtx)
+}
+
+func (f *File) find(ctx context.Context, flag string, evalCtx eval.Context) (interface{}, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, e := range f.matching(evalCtx) {
+		if sf, ok := e.Flags[flag]; ok {
+			ReportDetails(ctx, snapshotDetails(e, sf))
+			return sf.Value, true
+		}
+	}
+
+	ReportDetails(ctx, Details{Reason: ReasonError, ErrorCode: "FLAG_NOT_FOUND"})
+	return nil, false
+}
+
+// matching returns the snapshot entries whose context is a subset of
+// evalCtx, the most specific first.
+func (f *File) matching(evalCtx eval.Context) []SnapshotEntry {
+	var entries []SnapshotEntry
+	for _, e := range f.snapshot.Entries {
+		if contextSubset(e.Context, evalCtx) {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].Context) > len(entries[j].Context)
+	})
+
+	return entries
+}
+
+func snapshotDetails(e SnapshotEntry, sf SnapshotFlag) Details {
+	reason := ReasonStatic
+	if len(e.Context) > 0 {
+		reason = ReasonTargetingMatch
+	}
+
+	return Details{Variant: sf.Variant, Reason: reason}
+}
+
+// contextSubset reports whether all attributes of sub have equal values in
+// evalCtx. Values are compared by their JSON encoding, so that e.g. numbers
+// read from a snapshot equal integers passed by the caller.
+func contextSubset(sub, evalCtx eval.Context) bool {
+	for k, v := range sub {
+		other, ok := evalCtx[k]
+		if !ok {
+			return false
+		}
+
+		a, _ := json.Marshal(v)
+		b, _ := json.Marshal(other)
+		if string(a) != string(b) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+const testSnapshot = `entries:
+  - flags:
+      enabled:
+        value: false
+      seats:
+        value: 5
+  - context:
+      account_id: "123"
+    flags:
+      enabled:
+        value: true
+        variant: "on"
+`
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	if err := os.WriteFile(path, []byte(testSnapshot), 0o644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	f := NewFile(path)
+	if err := f.Init(context.TODO()); err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+
+	var d Details
+	ctx := WithDetails(context.TODO(), &d)
+	evalCtx := eval.Context{"account_id": "123", "plan": "pro"}
+
+	b, err := f.BooleanEvaluation(ctx, "enabled", false, evalCtx)
+	if err != nil || !b {
+		t.Fatalf("expected flag enabled to be true, got %v, %v", b, err)
+	}
+
+	if d.Variant != "on" || d.Reason != ReasonTargetingMatch {
+		t.Fatalf("expected variant on with reason TARGETING_MATCH, got %+v", d)
+	}
+
+	b, err = f.BooleanEvaluation(context.TODO(), "enabled", true, eval.Context{"account_id": "456"})
+	if err != nil || b {
+		t.Fatalf("expected flag enabled to be false, got %v, %v", b, err)
+	}
+
+	i, err := f.Int64Evaluation(context.TODO(), "seats", 0, evalCtx)
+	if err != nil || i != 5 {
+		t.Fatalf("expected flag seats to be 5, got %v, %v", i, err)
+	}
+
+	if _, err := f.StringEvaluation(context.TODO(), "missing", "", evalCtx); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrFlagNotFound, err)
+	}
+
+	all, err := f.AllEvaluations(context.TODO(), evalCtx)
+	if err != nil {
+		t.Fatalf("failed to evaluate all flags: %v", err)
+	}
+
+	if len(all) != 2 || all[0].Flag != "enabled" || all[0].Value != true || all[1].Flag != "seats" {
+		t.Fatalf("unexpected evaluations %+v", all)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s, err := ReadSnapshot(bytes.NewBufferString(testSnapshot), SnapshotFormatYAML)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+
+	for _, format := range []string{SnapshotFormatJSON, SnapshotFormatYAML} {
+		var buf bytes.Buffer
+		if err := WriteSnapshot(&buf, s, format); err != nil {
+			t.Fatalf("failed to write %s snapshot: %v", format, err)
+		}
+
+		read, err := ReadSnapshot(&buf, format)
+		if err != nil {
+			t.Fatalf("failed to read %s snapshot: %v", format, err)
+		}
+
+		if len(read.Entries) != 2 || read.Entries[1].Flags["enabled"].Variant != "on" {
+			t.Fatalf("unexpected %s snapshot %+v", format, read)
+		}
+	}
+
+	inMemory := NewInMemory()
+	inMemory.Import(s)
+
+	if v := inMemory.Flags["seats"].Value; v != float64(5) {
+		t.Fatalf("expected imported flag seats to be 5, got %v", v)
+	}
+
+	if v := inMemory.Flags["enabled"].Value; v != false {
+		t.Fatalf("expected imported flag enabled to be false, got %v", v)
+	}
+}
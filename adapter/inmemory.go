@@ -2,40 +2,123 @@ package adapter
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/kickplan/sdk-go/eval"
 )
 
-// Verify that InMemory implements Adapter.
-var _ Adapter = (*InMemory)(nil)
+// Metric operations recorded by InMemory.
+const (
+	MetricOperationSet       = "set"
+	MetricOperationIncrement = "increment"
+	MetricOperationDecrement = "decrement"
+)
+
+// Verify that InMemory implements Adapter, BulkEvaluator, MetricReader and
+// MetricRecorder.
+var (
+	_ Adapter        = (*InMemory)(nil)
+	_ BulkEvaluator  = (*InMemory)(nil)
+	_ MetricReader   = (*InMemory)(nil)
+	_ MetricRecorder = (*InMemory)(nil)
+)
 
 // InMemoryFlag structure represents a flag that is stored in memory.
 type InMemoryFlag struct {
 	Value interface{}
 }
 
-// InMemory is an adapter that stores flags in memory.
+// InMemoryMetric structure represents the value of a metric for an evaluation context.
+type InMemoryMetric struct {
+	Metric    string
+	Context   eval.Context
+	Value     int64
+	UpdatedAt time.Time
+}
+
+// InMemoryMetricUpdate structure represents a metric update recorded in memory.
+type InMemoryMetricUpdate struct {
+	Metric         string
+	Operation      string
+	Context        eval.Context
+	Value          int64
+	IdempotencyKey string
+	OccurredAt     time.Time
+	Unit           string
+	Attributes     map[string]interface{}
+}
+
+// InMemorySeries structure represents a counter, gauge or histogram stored in memory.
+type InMemorySeries struct {
+	Metric       string
+	Kind         MetricKind
+	Context      eval.Context
+	Value        float64
+	Observations []float64
+	UpdatedAt    time.Time
+}
+
+// InMemory is an adapter that stores flags and metrics in memory.
+//
+// Metrics are stored per metric and evaluation context. Contexts are
+// compared by value, so a metric updated for eval.Context{"account_id": "1"}
+// is read back with an equal context. Updates with an idempotency key that
+// was already applied are ignored; the most recent MaxIdempotencyKeys keys
+// are remembered.
+//
+// Counters, gauges and histograms recorded with RecordMetric are stored as
+// series apart from the metrics updated with SetMetric, IncMetric and
+// DecMetric; query them with GetSeries.
+//
+// The zero value, or a struct literal setting only Flags and Metrics, is
+// ready to use.
 type InMemory struct {
-	Flags   map[string]InMemoryFlag
+	Flags map[string]InMemoryFlag
+
+	// Metrics holds the values of the metrics updated without an evaluation
+	// context. Updates with an evaluation context are not reflected in it,
+	// not even as an aggregate.
+	//
+	// Deprecated: use GetMetric or ListMetrics, which cover all contexts.
 	Metrics map[string]int64
+
+	mu              sync.RWMutex
+	metrics         map[string]*InMemoryMetric
+	updates         []InMemoryMetricUpdate
+	series          map[string]*InMemorySeries
+	idempotencyKeys map[string]struct{}
+
+	// idempotencyOrder holds the remembered idempotency keys, oldest first.
+	idempotencyOrder []string
 }
 
+// MaxIdempotencyKeys is the number of idempotency keys remembered by
+// InMemory. Retries of older updates are applied again.
+const MaxIdempotencyKeys = 10000
+
 // NewInMemory returns a new InMemory adapter.
 func NewInMemory() *InMemory {
 	return &InMemory{
-		Flags:   make(map[string]InMemoryFlag),
-		Metrics: make(map[string]int64),
+		Flags:           make(map[string]InMemoryFlag),
+		Metrics:         make(map[string]int64),
+		metrics:         make(map[string]*InMemoryMetric),
+		series:          make(map[string]*InMemorySeries),
+		idempotencyKeys: make(map[string]struct{}),
 	}
 }
 
 // BooleanEvaluation returns the value of a boolean flag.
 func (i *InMemory) BooleanEvaluation(
-	_ context.Context,
+	ctx context.Context,
 	flag string,
 	defaultValue bool,
 	_ eval.Context,
 ) (bool, error) {
-	memoryFlag, ok := i.find(flag)
+	memoryFlag, ok := i.find(ctx, flag)
 	if !ok {
 		return defaultValue, nil
 	}
@@ -45,12 +128,12 @@ func (i *InMemory) BooleanEvaluation(
 
 // StringEvaluation returns the value of a string flag.
 func (i *InMemory) StringEvaluation(
-	_ context.Context,
+	ctx context.Context,
 	flag string,
 	defaultValue string,
 	_ eval.Context,
 ) (string, error) {
-	memoryFlag, ok := i.find(flag)
+	memoryFlag, ok := i.find(ctx, flag)
 	if !ok {
 		return defaultValue, nil
 	}
@@ -60,12 +143,12 @@ func (i *InMemory) StringEvaluation(
 
 // Int64Evaluation returns the value of a int64 flag.
 func (i *InMemory) Int64Evaluation(
-	_ context.Context,
+	ctx context.Context,
 	flag string,
 	defaultValue int64,
 	_ eval.Context,
 ) (int64, error) {
-	memoryFlag, ok := i.find(flag)
+	memoryFlag, ok := i.find(ctx, flag)
 	if !ok {
 		return defaultValue, nil
 	}
@@ -75,12 +158,12 @@ func (i *InMemory) Int64Evaluation(
 
 // ObjectEvaluation returns the value of a object flag.
 func (i *InMemory) ObjectEvaluation(
-	_ context.Context,
+	ctx context.Context,
 	flag string,
 	defaultValue interface{},
 	_ eval.Context,
 ) (interface{}, error) {
-	memoryFlag, ok := i.find(flag)
+	memoryFlag, ok := i.find(ctx, flag)
 	if !ok {
 		return defaultValue, nil
 	}
@@ -88,8 +171,36 @@ func (i *InMemory) ObjectEvaluation(
 	return memoryFlag.Value, nil
 }
 
+// AllEvaluations returns the values of all flags.
+func (i *InMemory) AllEvaluations(_ context.Context, _ eval.Context) ([]FlagEvaluation, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	evaluations := make([]FlagEvaluation, 0, len(i.Flags))
+	for flag, f := range i.Flags {
+		evaluations = append(evaluations, FlagEvaluation{
+			Flag:    flag,
+			Value:   f.Value,
+			Details: Details{Reason: ReasonStatic},
+		})
+	}
+
+	sort.Slice(evaluations, func(a, b int) bool {
+		return evaluations[a].Flag < evaluations[b].Flag
+	})
+
+	return evaluations, nil
+}
+
 // SetBoolean sets the value of a boolean flag.
 func (i *InMemory) SetBoolean(_ context.Context, flag string, value bool) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.Flags == nil {
+		i.Flags = make(map[string]InMemoryFlag)
+	}
+
 	i.Flags[flag] = InMemoryFlag{
 		Value: value,
 	}
@@ -97,28 +208,311 @@ func (i *InMemory) SetBoolean(_ context.Context, flag string, value bool) error
 }
 
 // SetMetric sets the value of a metric.
-func (i *InMemory) SetMetric(_ context.Context, metric string, value int64, _ eval.Context) error {
-	i.Metrics[metric] = value
-	return nil
+func (i *InMemory) SetMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	return i.updateMetric(metric, MetricOperationSet, value, evalCtx, MetricOptionsFromContext(ctx))
 }
 
 // IncMetric increments the value of a metric.
-func (i *InMemory) IncMetric(_ context.Context, metric string, value int64, _ eval.Context) error {
-	i.Metrics[metric] += value
-	return nil
+func (i *InMemory) IncMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	return i.updateMetric(metric, MetricOperationIncrement, value, evalCtx, MetricOptionsFromContext(ctx))
 }
 
 // DecMetric decrements the value of a metric.
-func (i *InMemory) DecMetric(_ context.Context, metric string, value int64, _ eval.Context) error {
-	i.Metrics[metric] -= value
+func (i *InMemory) DecMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
+	return i.updateMetric(metric, MetricOperationDecrement, value, evalCtx, MetricOptionsFromContext(ctx))
+}
+
+// ReadMetric returns the value of a metric.
+func (i *InMemory) ReadMetric(_ context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return Metric{}, err
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	m, ok := i.metrics[key]
+	if !ok {
+		return Metric{}, ErrMetricNotFound
+	}
+
+	return Metric{Value: m.Value, UpdatedAt: m.UpdatedAt}, nil
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (i *InMemory) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	if err := checkMetric(kind, value); err != nil {
+		return err
+	}
+
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	o := MetricOptionsFromContext(ctx)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.applied(o.IdempotencyKey) {
+		return nil
+	}
+
+	if i.series == nil {
+		i.series = make(map[string]*InMemorySeries)
+	}
+
+	m, ok := i.series[key]
+	if ok && m.Kind != kind {
+		return fmt.Errorf("%w: %q is a %s", ErrMetricKindMismatch, metric, m.Kind)
+	}
+
+	if !ok {
+		m = &InMemorySeries{Metric: metric, Kind: kind, Context: evalCtx}
+		i.series[key] = m
+	}
+
+	switch kind {
+	case MetricKindCounter:
+		m.Value += value
+	case MetricKindGauge:
+		m.Value = value
+	case MetricKindHistogram:
+		m.Observations = append(m.Observations, value)
+	}
+
+	occurredAt := o.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	if occurredAt.After(m.UpdatedAt) {
+		m.UpdatedAt = occurredAt
+	}
+
+	// remember the key only once the update is applied, so that a retry of
+	// a failed update is not ignored
+	i.remember(o.IdempotencyKey)
+
 	return nil
 }
 
-func (i *InMemory) find(flag string) (InMemoryFlag, bool) {
+// GetSeries returns a counter, gauge or histogram for an evaluation context.
+func (i *InMemory) GetSeries(metric string, evalCtx eval.Context) (InMemorySeries, bool) {
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return InMemorySeries{}, false
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	m, ok := i.series[key]
+	if !ok {
+		return InMemorySeries{}, false
+	}
+
+	series := *m
+	series.Observations = append([]float64(nil), m.Observations...)
+
+	return series, true
+}
+
+// GetMetric returns the value of a metric for an evaluation context.
+func (i *InMemory) GetMetric(metric string, evalCtx eval.Context) int64 {
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return 0
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	m, ok := i.metrics[key]
+	if !ok {
+		return 0
+	}
+
+	return m.Value
+}
+
+// ListMetrics returns the values of all metrics for all evaluation contexts.
+func (i *InMemory) ListMetrics() []InMemoryMetric {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	metrics := make([]InMemoryMetric, 0, len(i.metrics))
+	for _, m := range i.metrics {
+		metrics = append(metrics, *m)
+	}
+
+	return metrics
+}
+
+// MetricUpdates returns all metric updates in the order they were made.
+func (i *InMemory) MetricUpdates() []InMemoryMetricUpdate {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return append([]InMemoryMetricUpdate(nil), i.updates...)
+}
+
+// MetricHistory returns the updates of a metric for an evaluation context in
+// the order they were made.
+func (i *InMemory) MetricHistory(metric string, evalCtx eval.Context) []InMemoryMetricUpdate {
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return nil
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var history []InMemoryMetricUpdate
+	for _, u := range i.updates {
+		// updates were applied, so their contexts can be encoded
+		if k, _ := MetricKey(u.Metric, u.Context); k == key {
+			history = append(history, u)
+		}
+	}
+
+	return history
+}
+
+func (i *InMemory) updateMetric(
+	metric string,
+	operation string,
+	value int64,
+	evalCtx eval.Context,
+	o MetricOptions,
+) error {
+	key, err := MetricKey(metric, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.applied(o.IdempotencyKey) {
+		return nil
+	}
+	i.remember(o.IdempotencyKey)
+
+	if i.metrics == nil {
+		i.metrics = make(map[string]*InMemoryMetric)
+	}
+
+	m, ok := i.metrics[key]
+	if !ok {
+		m = &InMemoryMetric{Metric: metric, Context: evalCtx}
+		i.metrics[key] = m
+	}
+
+	switch operation {
+	case MetricOperationSet:
+		m.Value = value
+	case MetricOperationIncrement:
+		m.Value += value
+	case MetricOperationDecrement:
+		m.Value -= value
+	}
+
+	occurredAt := o.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	if occurredAt.After(m.UpdatedAt) {
+		m.UpdatedAt = occurredAt
+	}
+
+	if len(evalCtx) == 0 {
+		if i.Metrics == nil {
+			i.Metrics = make(map[string]int64)
+		}
+		i.Metrics[metric] = m.Value
+	}
+
+	i.updates = append(i.updates, InMemoryMetricUpdate{
+		Metric:         metric,
+		Operation:      operation,
+		Context:        evalCtx,
+		Value:          value,
+		IdempotencyKey: o.IdempotencyKey,
+		OccurredAt:     occurredAt,
+		Unit:           o.Unit,
+		Attributes:     o.Attributes,
+	})
+
+	return nil
+}
+
+// applied reports whether an update with the idempotency key was already
+// applied.
+func (i *InMemory) applied(idempotencyKey string) bool {
+	if idempotencyKey == "" {
+		return false
+	}
+
+	_, ok := i.idempotencyKeys[idempotencyKey]
+	return ok
+}
+
+// remember remembers the idempotency key of an applied update.
+func (i *InMemory) remember(idempotencyKey string) {
+	if idempotencyKey == "" {
+		return
+	}
+
+	if len(i.idempotencyOrder) >= MaxIdempotencyKeys {
+		delete(i.idempotencyKeys, i.idempotencyOrder[0])
+		i.idempotencyOrder = i.idempotencyOrder[1:]
+	}
+
+	if i.idempotencyKeys == nil {
+		i.idempotencyKeys = make(map[string]struct{})
+	}
+
+	i.idempotencyKeys[idempotencyKey] = struct{}{}
+	i.idempotencyOrder = append(i.idempotencyOrder, idempotencyKey)
+}
+
+func (i *InMemory) find(ctx context.Context, flag string) (InMemoryFlag, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
 	memoryFlag, ok := i.Flags[flag]
 	if !ok {
+		ReportDetails(ctx, Details{Reason: ReasonDefault})
 		return InMemoryFlag{}, false
 	}
 
+	ReportDetails(ctx, Details{Reason: ReasonStatic})
 	return memoryFlag, true
 }
+
+// encodeContext returns a canonical representation of an evaluation
+// context. Maps are encoded with sorted keys, so equal contexts have equal
+// representations. It fails for contexts that cannot be encoded as JSON, so
+// that they are not mistaken for the empty context.
+func encodeContext(evalCtx eval.Context) (string, error) {
+	if len(evalCtx) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(evalCtx)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode evaluation context: %w", err)
+	}
+
+	return string(b), nil
+}
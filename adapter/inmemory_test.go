@@ -0,0 +1,319 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func TestInMemoryMetrics(t *testing.T) {
+	inMemory := NewInMemory()
+
+	account := eval.Context{"account_id": "account"}
+	other := eval.Context{"account_id": "other"}
+
+	for _, update := range []func() error{
+		func() error { return inMemory.SetMetric(context.TODO(), "seats", 10, account) },
+		func() error { return inMemory.IncMetric(context.TODO(), "seats", 5, account) },
+		func() error { return inMemory.DecMetric(context.TODO(), "seats", 3, account) },
+		func() error { return inMemory.IncMetric(context.TODO(), "seats", 1, other) },
+	} {
+		if err := update(); err != nil {
+			t.Fatalf("failed to update metric: %v", err)
+		}
+	}
+
+	if v := inMemory.GetMetric("seats", eval.Context{"account_id": "account"}); v != 12 {
+		t.Fatalf("expected metric seats for account to be 12, got %d", v)
+	}
+
+	if v := inMemory.GetMetric("seats", other); v != 1 {
+		t.Fatalf("expected metric seats for other to be 1, got %d", v)
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != 0 {
+		t.Fatalf("expected metric seats without context to be 0, got %d", v)
+	}
+
+	if metrics := inMemory.ListMetrics(); len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	if updates := inMemory.MetricUpdates(); len(updates) != 4 {
+		t.Fatalf("expected 4 metric updates, got %d", len(updates))
+	}
+
+	expected := []InMemoryMetricUpdate{
+		{Metric: "seats", Operation: MetricOperationSet, Value: 10},
+		{Metric: "seats", Operation: MetricOperationIncrement, Value: 5},
+		{Metric: "seats", Operation: MetricOperationDecrement, Value: 3},
+	}
+
+	history := inMemory.MetricHistory("seats", account)
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d metric updates for account, got %d", len(expected), len(history))
+	}
+
+	for i, u := range history {
+		if u.Metric != expected[i].Metric || u.Operation != expected[i].Operation || u.Value != expected[i].Value {
+			t.Fatalf("expected metric update %d to be %+v, got %+v", i, expected[i], u)
+		}
+	}
+}
+
+func TestInMemoryDeprecatedMetrics(t *testing.T) {
+	inMemory := NewInMemory()
+
+	if err := inMemory.SetMetric(context.TODO(), "seats", 10, nil); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	if err := inMemory.IncMetric(context.TODO(), "seats", 5, eval.Context{"account_id": "account"}); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if v := inMemory.Metrics["seats"]; v != 10 {
+		t.Fatalf("expected deprecated metric seats to be 10, got %d", v)
+	}
+
+	if err := inMemory.SetMetric(context.TODO(), "storage", 3, eval.Context{"account_id": "account"}); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	if _, ok := inMemory.Metrics["storage"]; ok {
+		t.Fatalf("expected metric storage updated with a context to be missing from the deprecated metrics")
+	}
+}
+
+func TestInMemoryZeroValue(t *testing.T) {
+	for name, inMemory := range map[string]*InMemory{
+		"zero":    {},
+		"literal": {Flags: map[string]InMemoryFlag{"enabled": {Value: true}}, Metrics: map[string]int64{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := withIdempotencyKey("key")
+			evalCtx := eval.Context{"account_id": "account"}
+
+			if err := inMemory.SetBoolean(context.TODO(), "enabled", true); err != nil {
+				t.Fatalf("failed to set flag: %v", err)
+			}
+
+			if err := inMemory.SetMetric(ctx, "seats", 10, nil); err != nil {
+				t.Fatalf("failed to set metric: %v", err)
+			}
+
+			if err := inMemory.IncMetric(ctx, "seats", 5, nil); err != nil {
+				t.Fatalf("failed to increment metric: %v", err)
+			}
+
+			if err := inMemory.RecordMetric(context.TODO(), "api_calls", MetricKindCounter, 1, evalCtx); err != nil {
+				t.Fatalf("failed to record metric: %v", err)
+			}
+
+			if v := inMemory.GetMetric("seats", nil); v != 10 {
+				t.Fatalf("expected metric seats to be 10, got %d", v)
+			}
+
+			if v := inMemory.Metrics["seats"]; v != 10 {
+				t.Fatalf("expected deprecated metric seats to be 10, got %d", v)
+			}
+
+			if s, ok := inMemory.GetSeries("api_calls", evalCtx); !ok || s.Value != 1 {
+				t.Fatalf("expected series api_calls to be 1, got %v", s.Value)
+			}
+
+			if v, _ := inMemory.BooleanEvaluation(context.TODO(), "enabled", false, nil); !v {
+				t.Fatalf("expected flag enabled to be true")
+			}
+		})
+	}
+}
+
+func TestInMemoryUnencodableContext(t *testing.T) {
+	inMemory := NewInMemory()
+
+	evalCtx := eval.Context{"ratio": math.NaN()}
+	if err := inMemory.SetMetric(context.TODO(), "seats", 10, evalCtx); err == nil {
+		t.Fatalf("expected error for unencodable context, got nil")
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != 0 {
+		t.Fatalf("expected metric seats without context to be 0, got %d", v)
+	}
+
+	if metrics := inMemory.ListMetrics(); len(metrics) != 0 {
+		t.Fatalf("expected no metrics, got %d", len(metrics))
+	}
+}
+
+func TestInMemoryInt64FromFloat(t *testing.T) {
+	inMemory := NewInMemory()
+	inMemory.Flags["whole"] = InMemoryFlag{Value: float64(3)}
+	inMemory.Flags["fraction"] = InMemoryFlag{Value: 3.5}
+	inMemory.Flags["huge"] = InMemoryFlag{Value: 1e19}
+
+	if v, err := inMemory.Int64Evaluation(context.TODO(), "whole", 0, nil); err != nil || v != 3 {
+		t.Fatalf("expected flag whole to be 3, got %d and %v", v, err)
+	}
+
+	for _, flag := range []string{"fraction", "huge"} {
+		v, err := inMemory.Int64Evaluation(context.TODO(), flag, -1, nil)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("expected error %v for flag %s, got %v", ErrTypeMismatch, flag, err)
+		}
+
+		if v != -1 {
+			t.Fatalf("expected default value for flag %s, got %d", flag, v)
+		}
+	}
+}
+
+func TestInMemoryIdempotencyKey(t *testing.T) {
+	inMemory := NewInMemory()
+
+	for range 3 {
+		if err := inMemory.IncMetric(withIdempotencyKey("key"), "seats", 1, nil); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	if err := inMemory.IncMetric(withIdempotencyKey("other"), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != 2 {
+		t.Fatalf("expected metric seats to be 2, got %d", v)
+	}
+
+	if updates := inMemory.MetricUpdates(); len(updates) != 2 {
+		t.Fatalf("expected 2 metric updates, got %d", len(updates))
+	}
+}
+
+func TestInMemoryIdempotencyKeysBounded(t *testing.T) {
+	inMemory := NewInMemory()
+
+	for n := range MaxIdempotencyKeys + 1 {
+		if err := inMemory.IncMetric(withIdempotencyKey(fmt.Sprint(n)), "seats", 1, nil); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	if n := len(inMemory.idempotencyKeys); n != MaxIdempotencyKeys {
+		t.Fatalf("expected %d idempotency keys, got %d", MaxIdempotencyKeys, n)
+	}
+
+	// the oldest key was forgotten, the most recent one is remembered
+	for _, key := range []string{"0", fmt.Sprint(MaxIdempotencyKeys)} {
+		if err := inMemory.IncMetric(withIdempotencyKey(key), "seats", 1, nil); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != MaxIdempotencyKeys+2 {
+		t.Fatalf("expected metric seats to be %d, got %d", MaxIdempotencyKeys+2, v)
+	}
+}
+
+func TestInMemoryOccurredAt(t *testing.T) {
+	inMemory := NewInMemory()
+
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := WithMetricOptions(context.TODO(), NewMetricOptions(WithOccurredAt(occurredAt), WithUnit("bytes")))
+	if err := inMemory.IncMetric(ctx, "storage", 1024, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	m, err := inMemory.ReadMetric(context.TODO(), "storage", nil)
+	if err != nil {
+		t.Fatalf("failed to read metric: %v", err)
+	}
+
+	if !m.UpdatedAt.Equal(occurredAt) {
+		t.Fatalf("expected metric updated at to be %s, got %s", occurredAt, m.UpdatedAt)
+	}
+
+	history := inMemory.MetricHistory("storage", nil)
+	if len(history) != 1 || !history[0].OccurredAt.Equal(occurredAt) || history[0].Unit != "bytes" {
+		t.Fatalf("expected metric update to have occurred at %s in bytes, got %+v", occurredAt, history)
+	}
+}
+
+func TestInMemoryRecordMetric(t *testing.T) {
+	inMemory := NewInMemory()
+	evalCtx := eval.Context{"account_id": "account"}
+
+	for _, v := range []float64{1.5, 2.5} {
+		if err := inMemory.RecordMetric(context.TODO(), "api_calls", MetricKindCounter, v, evalCtx); err != nil {
+			t.Fatalf("failed to record counter: %v", err)
+		}
+
+		if err := inMemory.RecordMetric(context.TODO(), "storage", MetricKindGauge, v, evalCtx); err != nil {
+			t.Fatalf("failed to record gauge: %v", err)
+		}
+
+		if err := inMemory.RecordMetric(context.TODO(), "latency", MetricKindHistogram, v, evalCtx); err != nil {
+			t.Fatalf("failed to record histogram: %v", err)
+		}
+	}
+
+	if s, _ := inMemory.GetSeries("api_calls", evalCtx); s.Value != 4 {
+		t.Fatalf("expected counter api_calls to be 4, got %v", s.Value)
+	}
+
+	if s, _ := inMemory.GetSeries("storage", evalCtx); s.Value != 2.5 {
+		t.Fatalf("expected gauge storage to be 2.5, got %v", s.Value)
+	}
+
+	if s, _ := inMemory.GetSeries("latency", evalCtx); len(s.Observations) != 2 || s.Observations[1] != 2.5 {
+		t.Fatalf("expected histogram latency to have observations [1.5 2.5], got %v", s.Observations)
+	}
+
+	err := inMemory.RecordMetric(context.TODO(), "api_calls", MetricKindCounter, -1, evalCtx)
+	if !errors.Is(err, ErrNegativeCounter) {
+		t.Fatalf("expected error %v, got %v", ErrNegativeCounter, err)
+	}
+
+	err = inMemory.RecordMetric(context.TODO(), "api_calls", MetricKindGauge, 1, evalCtx)
+	if !errors.Is(err, ErrMetricKindMismatch) {
+		t.Fatalf("expected error %v, got %v", ErrMetricKindMismatch, err)
+	}
+
+	err = inMemory.RecordMetric(context.TODO(), "latency", MetricKindHistogram, math.NaN(), evalCtx)
+	if !errors.Is(err, ErrInvalidMetricValue) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidMetricValue, err)
+	}
+}
+
+func TestInMemoryRecordMetricKindMismatchIdempotency(t *testing.T) {
+	inMemory := NewInMemory()
+
+	if err := inMemory.RecordMetric(context.TODO(), "storage", MetricKindGauge, 1, nil); err != nil {
+		t.Fatalf("failed to record metric: %v", err)
+	}
+
+	err := inMemory.RecordMetric(withIdempotencyKey("key"), "storage", MetricKindCounter, 1, nil)
+	if !errors.Is(err, ErrMetricKindMismatch) {
+		t.Fatalf("expected error %v, got %v", ErrMetricKindMismatch, err)
+	}
+
+	// the failed update did not consume its idempotency key
+	if err := inMemory.RecordMetric(withIdempotencyKey("key"), "api_calls", MetricKindCounter, 1, nil); err != nil {
+		t.Fatalf("failed to record metric: %v", err)
+	}
+
+	if s, ok := inMemory.GetSeries("api_calls", nil); !ok || s.Value != 1 {
+		t.Fatalf("expected counter api_calls to be 1, got %+v", s)
+	}
+}
+
+// withIdempotencyKey returns a context that carries an idempotency key to
+// metric updates.
+func withIdempotencyKey(key string) context.Context {
+	return WithMetricOptions(context.TODO(), NewMetricOptions(WithIdempotencyKey(key)))
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/kickplan/sdk-go/eval"
@@ -26,11 +27,48 @@ const (
 	DefaultTimeout = 5 * time.Second
 )
 
+// Routes of the Kickplan API passed to request hooks.
+const (
+	routeFeatures        = "/features"
+	routeFeature         = "/features/{flag}"
+	routeMetricSet       = "/metrics/{metric}/set"
+	routeMetricIncrement = "/metrics/{metric}/increment"
+	routeMetricDecrement = "/metrics/{metric}/decrement"
+	routeMetricValue     = "/metrics/{metric}/value"
+	routeMetricRecord    = "/metrics/{metric}/record"
+)
+
+// HTTPError is returned when the Kickplan API responds with an unexpected
+// status code.
+type HTTPError struct {
+	StatusCode int
+}
+
+// Error returns a description of the status code.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Permanent reports whether retrying the request cannot succeed, i.e. the
+// API rejected it with a client error other than a timeout or rate limit.
+func (e *HTTPError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
 // ErrFlagNotFound is returned when a flag is not found.
 var ErrFlagNotFound = fmt.Errorf("FLAG_NOT_FOUND")
 
-// Verify that Kickplan implements Adapter.
-var _ Adapter = (*Kickplan)(nil)
+// Verify that Kickplan implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder, StatusReporter and Lifecycle.
+var (
+	_ Adapter        = (*Kickplan)(nil)
+	_ BulkEvaluator  = (*Kickplan)(nil)
+	_ MetricReader   = (*Kickplan)(nil)
+	_ MetricRecorder = (*Kickplan)(nil)
+	_ StatusReporter = (*Kickplan)(nil)
+	_ Lifecycle      = (*Kickplan)(nil)
+)
 
 // FeatureResolutionRequest represents a request body for the feature resolution endpoint.
 type FeatureResolutionRequest struct {
@@ -42,13 +80,45 @@ type FeatureResolutionRequest struct {
 type FeatureResolutionResponse struct {
 	ErrorCode string      `json:"error_code"`
 	Key       string      `json:"key"`
+	Reason    string      `json:"reason"`
 	Value     interface{} `json:"value"`
+	Variant   string      `json:"variant"`
 }
 
 // MetricUpdateRequest represents a request body for the metric update endpoint.
+// OccurredAt is encoded in RFC 3339 format.
 type MetricUpdateRequest struct {
+	Context        eval.Context           `json:"context"`
+	Value          int64                  `json:"value"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	OccurredAt     time.Time              `json:"occurred_at,omitzero"`
+	Unit           string                 `json:"unit,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// MetricRecordRequest represents a request body for the metric record endpoint.
+// OccurredAt is encoded in RFC 3339 format.
+type MetricRecordRequest struct {
+	Context        eval.Context           `json:"context"`
+	Kind           MetricKind             `json:"kind"`
+	Value          float64                `json:"value"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	OccurredAt     time.Time              `json:"occurred_at,omitzero"`
+	Unit           string                 `json:"unit,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// MetricValueRequest represents a request body for the metric value endpoint.
+type MetricValueRequest struct {
 	Context eval.Context `json:"context"`
-	Value   int64        `json:"value"`
+}
+
+// MetricValueResponse represents a response body for the metric value endpoint.
+type MetricValueResponse struct {
+	ErrorCode string    `json:"error_code"`
+	Key       string    `json:"key"`
+	Value     int64     `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // HTTPClient is an interface that defines the methods that a HTTP client must implement.
@@ -56,12 +126,44 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// RequestHook is called before a request is sent to the Kickplan API. The
+// route identifies the endpoint, e.g. "/features/{flag}". The hook returns
+// the context to send the request with and an optional function that is
+// called with the outcome of the request.
+type RequestHook func(
+	ctx context.Context,
+	route string,
+	req *http.Request,
+) (context.Context, func(*http.Response, error))
+
 // Kickplan is an adapter that uses Kickplan API for flags.
 type Kickplan struct {
-	client    HTTPClient
-	endpoint  string
-	token     string
-	userAgent string
+	client       HTTPClient
+	endpoint     string
+	token        string
+	userAgent    string
+	requestHooks []RequestHook
+
+	status StatusTracker
+}
+
+// KickplanOption is a function that configures a Kickplan adapter.
+type KickplanOption func(*Kickplan)
+
+// WithHTTPClient sets the HTTP client used to send requests. The timeout
+// passed to NewKickplan is not applied to it.
+func WithHTTPClient(client HTTPClient) KickplanOption {
+	return func(k *Kickplan) {
+		k.client = client
+	}
+}
+
+// WithRequestHook adds a hook that is called for every request sent to the
+// Kickplan API. Hooks are called in the order they were added.
+func WithRequestHook(hook RequestHook) KickplanOption {
+	return func(k *Kickplan) {
+		k.requestHooks = append(k.requestHooks, hook)
+	}
 }
 
 // NewKickplan returns a new Kickplan adapter.
@@ -70,6 +172,7 @@ func NewKickplan(
 	token string,
 	userAgent string,
 	timeout string,
+	opt ...KickplanOption,
 ) *Kickplan {
 	if endpoint == "" {
 		endpoint = DefaultEndpoint
@@ -89,14 +192,61 @@ func NewKickplan(
 		}
 	}
 
-	return &Kickplan{
-		client: &http.Client{
-			Timeout: timeoutDuration,
-		},
+	client := &http.Client{
+		Timeout: timeoutDuration,
+	}
+
+	// use a transport of its own, so that Shutdown does not close the
+	// connections of other clients
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		client.Transport = t.Clone()
+	}
+
+	k := &Kickplan{
+		client:    client,
 		endpoint:  endpoint,
 		token:     token,
 		userAgent: userAgent,
 	}
+
+	for _, o := range opt {
+		o(k)
+	}
+
+	return k
+}
+
+// Init checks that the Kickplan API is reachable with a bulk evaluation and
+// sets the status of the adapter accordingly. It does not fail when the API
+// is unreachable, so that a Fallback chain can serve flags from its other
+// adapters; use Status or the WaitForReady method of the client to require
+// the API.
+func (k *Kickplan) Init(ctx context.Context) error {
+	_, _ = k.AllEvaluations(ctx, nil)
+	return nil
+}
+
+// Status returns the status of the adapter. It is not ready until the first
+// request, ready once the API responded, and stale, or failed if it was never
+// ready, while the API is unreachable, rejects the token or responds with a
+// server error.
+func (k *Kickplan) Status() (Status, error) {
+	return k.status.Status()
+}
+
+// WatchStatus calls fn with every change of the status until stop is called.
+func (k *Kickplan) WatchStatus(fn func(StatusEvent)) (stop func()) {
+	return k.status.WatchStatus(fn)
+}
+
+// Shutdown closes the idle connections of the HTTP client, if it supports
+// it like http.Client does.
+func (k *Kickplan) Shutdown(_ context.Context) error {
+	if c, ok := k.client.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+
+	return nil
 }
 
 // BooleanEvaluation returns the value of a boolean flag.
@@ -172,29 +322,34 @@ func (k *Kickplan) ResolveFeature(
 		Detailed: true,
 	}
 
-	resp, err := k.sendRequest(ctx, url, body)
+	resp, err := k.sendRequest(ctx, routeFeature, url, body)
 	if err != nil {
+		ReportDetails(ctx, Details{Reason: ReasonError})
 		return defaultValue, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return defaultValue, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		ReportDetails(ctx, Details{Reason: ReasonError})
+		return defaultValue, &HTTPError{StatusCode: resp.StatusCode}
 	}
 
 	// read response body
 	b, err := k.readResponseBody(resp)
 	if err != nil {
+		ReportDetails(ctx, Details{Reason: ReasonError})
 		return defaultValue, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// decode response
 	var response FeatureResolutionResponse
 	if err := json.Unmarshal(b, &response); err != nil {
+		ReportDetails(ctx, Details{Reason: ReasonError})
 		return defaultValue, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if response.ErrorCode != "" {
+		ReportDetails(ctx, Details{Reason: ReasonError, ErrorCode: response.ErrorCode})
 		if response.ErrorCode == "FLAG_NOT_FOUND" {
 			return defaultValue, ErrFlagNotFound
 		}
@@ -202,9 +357,71 @@ func (k *Kickplan) ResolveFeature(
 		return defaultValue, errors.New(response.ErrorCode)
 	}
 
+	reason := response.Reason
+	if reason == "" {
+		reason = ReasonTargetingMatch
+	}
+	ReportDetails(ctx, Details{Variant: response.Variant, Reason: reason})
+
 	return response.Value, nil
 }
 
+// AllEvaluations resolves all feature flags from the Kickplan API.
+func (k *Kickplan) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	url := fmt.Sprintf("%s/features", k.endpoint)
+	body := FeatureResolutionRequest{
+		Context:  evalCtx,
+		Detailed: true,
+	}
+
+	resp, err := k.sendRequest(ctx, routeFeatures, url, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: resp.StatusCode}
+	}
+
+	b, err := k.readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var response []FeatureResolutionResponse
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	evaluations := make([]FlagEvaluation, 0, len(response))
+	for _, r := range response {
+		reason := r.Reason
+		switch {
+		case r.ErrorCode != "":
+			reason = ReasonError
+		case reason == "":
+			reason = ReasonTargetingMatch
+		}
+
+		evaluations = append(evaluations, FlagEvaluation{
+			Flag:  r.Key,
+			Value: r.Value,
+			Details: Details{
+				Variant:   r.Variant,
+				Reason:    reason,
+				ErrorCode: r.ErrorCode,
+			},
+		})
+	}
+
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Flag < evaluations[j].Flag
+	})
+
+	return evaluations, nil
+}
+
 // SetBoolean sets the value of a boolean flag.
 func (k *Kickplan) SetBoolean(_ context.Context, _ string, _ bool) error {
 	return fmt.Errorf("not implemented")
@@ -213,19 +430,16 @@ func (k *Kickplan) SetBoolean(_ context.Context, _ string, _ bool) error {
 // SetMetric sets the value of a metric.
 func (k *Kickplan) SetMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
 	url := fmt.Sprintf("%s/metrics/%s/set", k.endpoint, metric)
-	body := MetricUpdateRequest{
-		Context: evalCtx,
-		Value:   value,
-	}
+	body := newMetricUpdateRequest(value, evalCtx, MetricOptionsFromContext(ctx))
 
-	resp, err := k.sendRequest(ctx, url, body)
+	resp, err := k.sendRequest(ctx, routeMetricSet, url, body)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &HTTPError{StatusCode: resp.StatusCode}
 	}
 
 	return nil
@@ -234,19 +448,16 @@ func (k *Kickplan) SetMetric(ctx context.Context, metric string, value int64, ev
 // IncMetric increments the value of a metric.
 func (k *Kickplan) IncMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
 	url := fmt.Sprintf("%s/metrics/%s/increment", k.endpoint, metric)
-	body := MetricUpdateRequest{
-		Context: evalCtx,
-		Value:   value,
-	}
+	body := newMetricUpdateRequest(value, evalCtx, MetricOptionsFromContext(ctx))
 
-	resp, err := k.sendRequest(ctx, url, body)
+	resp, err := k.sendRequest(ctx, routeMetricIncrement, url, body)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &HTTPError{StatusCode: resp.StatusCode}
 	}
 
 	return nil
@@ -255,25 +466,111 @@ func (k *Kickplan) IncMetric(ctx context.Context, metric string, value int64, ev
 // DecMetric decrements the value of a metric.
 func (k *Kickplan) DecMetric(ctx context.Context, metric string, value int64, evalCtx eval.Context) error {
 	url := fmt.Sprintf("%s/metrics/%s/decrement", k.endpoint, metric)
-	body := MetricUpdateRequest{
-		Context: evalCtx,
-		Value:   value,
+	body := newMetricUpdateRequest(value, evalCtx, MetricOptionsFromContext(ctx))
+
+	resp, err := k.sendRequest(ctx, routeMetricDecrement, url, body)
+	if err != nil {
+		return err
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	resp, err := k.sendRequest(ctx, url, body)
+	if resp.StatusCode != http.StatusAccepted {
+		return &HTTPError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+func newMetricUpdateRequest(value int64, evalCtx eval.Context, o MetricOptions) MetricUpdateRequest {
+	return MetricUpdateRequest{
+		Context:        evalCtx,
+		Value:          value,
+		IdempotencyKey: o.IdempotencyKey,
+		OccurredAt:     o.OccurredAt,
+		Unit:           o.Unit,
+		Attributes:     o.Attributes,
+	}
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (k *Kickplan) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	if err := checkMetric(kind, value); err != nil {
+		return err
+	}
+
+	o := MetricOptionsFromContext(ctx)
+
+	url := fmt.Sprintf("%s/metrics/%s/record", k.endpoint, metric)
+	body := MetricRecordRequest{
+		Context:        evalCtx,
+		Kind:           kind,
+		Value:          value,
+		IdempotencyKey: o.IdempotencyKey,
+		OccurredAt:     o.OccurredAt,
+		Unit:           o.Unit,
+		Attributes:     o.Attributes,
+	}
+
+	resp, err := k.sendRequest(ctx, routeMetricRecord, url, body)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &HTTPError{StatusCode: resp.StatusCode}
 	}
 
 	return nil
 }
 
-func (k *Kickplan) sendRequest(ctx context.Context, url string, body interface{}) (*http.Response, error) {
+// ReadMetric returns the value of a metric.
+func (k *Kickplan) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	url := fmt.Sprintf("%s/metrics/%s/value", k.endpoint, metric)
+	body := MetricValueRequest{
+		Context: evalCtx,
+	}
+
+	resp, err := k.sendRequest(ctx, routeMetricValue, url, body)
+	if err != nil {
+		return Metric{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return Metric{}, &HTTPError{StatusCode: resp.StatusCode}
+	}
+
+	// read response body
+	b, err := k.readResponseBody(resp)
+	if err != nil {
+		return Metric{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// decode response
+	var response MetricValueResponse
+	if err := json.Unmarshal(b, &response); err != nil {
+		return Metric{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.ErrorCode != "" {
+		if response.ErrorCode == "METRIC_NOT_FOUND" {
+			return Metric{}, ErrMetricNotFound
+		}
+
+		return Metric{}, errors.New(response.ErrorCode)
+	}
+
+	return Metric{Value: response.Value, UpdatedAt: response.UpdatedAt}, nil
+}
+
+func (k *Kickplan) sendRequest(ctx context.Context, route, url string, body interface{}) (*http.Response, error) {
 	// encode body
 	b, err := json.Marshal(body)
 	if err != nil {
@@ -287,8 +584,22 @@ func (k *Kickplan) sendRequest(ctx context.Context, url string, body interface{}
 
 	k.setHeaders(req)
 
+	var done []func(*http.Response, error)
+	for _, hook := range k.requestHooks {
+		var d func(*http.Response, error)
+		ctx, d = hook(ctx, route, req)
+		req = req.WithContext(ctx)
+		if d != nil {
+			done = append(done, d)
+		}
+	}
+
 	// send request
 	resp, err := k.client.Do(req)
+	for i := len(done) - 1; i >= 0; i-- {
+		done[i](resp, err)
+	}
+	k.updateStatus(ctx, resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -296,12 +607,40 @@ func (k *Kickplan) sendRequest(ctx context.Context, url string, body interface{}
 	return resp, nil
 }
 
+// updateStatus sets the status of the adapter from the result of a request:
+// server errors and rejected tokens count as failures. Requests canceled by
+// the caller do not change it.
+func (k *Kickplan) updateStatus(ctx context.Context, resp *http.Response, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		code := resp.StatusCode
+		if code < http.StatusInternalServerError && code != http.StatusUnauthorized && code != http.StatusForbidden {
+			k.status.SetStatus(StatusReady, nil)
+			return
+		}
+		err = &HTTPError{StatusCode: code}
+	}
+
+	s := StatusError
+	if current, _ := k.status.Status(); current.Serving() {
+		s = StatusStale
+	}
+	k.status.SetStatus(s, err)
+}
+
 func (k *Kickplan) setHeaders(req *http.Request) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", k.token))
 	req.Header.Set("User-Agent", k.userAgent)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept-Encoding", "gzip;q=1.0,deflate;q=0.6,identity;q=0.3")
 	req.Header.Set("Accept", "application/json")
+
+	if o := CallOptionsFromContext(req.Context()); o.BypassCache || o.RequireFresh {
+		req.Header.Set("Cache-Control", "no-cache")
+	}
 }
 
 func (k *Kickplan) readResponseBody(resp *http.Response) ([]byte, error) {
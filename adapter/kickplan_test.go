@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/kickplan/sdk-go/eval"
 )
@@ -237,6 +238,10 @@ func TestMetricIncrement(t *testing.T) {
 			t.Fatalf("expected request value to be 20, got %d", body.Value)
 		}
 
+		if body.IdempotencyKey != "key" {
+			t.Fatalf("expected request idempotency key to be key, got %s", body.IdempotencyKey)
+		}
+
 		return &http.Response{
 			StatusCode: http.StatusAccepted,
 			Body:       io.NopCloser(bytes.NewReader([]byte(``))),
@@ -245,7 +250,7 @@ func TestMetricIncrement(t *testing.T) {
 
 	adapter := Kickplan{client: &mockClient{}, endpoint: "https://api.domain.com"}
 
-	if err := adapter.IncMetric(context.TODO(), "metric", 20, eval.Context{
+	if err := adapter.IncMetric(withIdempotencyKey("key"), "metric", 20, eval.Context{
 		"account_id": "account",
 	}); err != nil {
 		t.Fatal(err)
@@ -286,3 +291,252 @@ func TestMetricDecrement(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMetricValue(t *testing.T) {
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() != "https://api.domain.com/metrics/metric/value" {
+			t.Fatalf("expected request endpoint to be https://api.domain.com/metrics/metric/value, got %s", req.URL.String())
+		}
+
+		if req.Method != http.MethodPost {
+			t.Fatalf("expected request method to be POST, got %s", req.Method)
+		}
+
+		var body MetricValueRequest
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+
+		if body.Context["account_id"] != "account" {
+			t.Fatalf("expected request context account_id to be account, got %s", body.Context["account_id"])
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(bytes.NewReader([]byte(`{
+	"error_code": "",
+	"key": "metric",
+	"value": 42,
+	"updated_at": "2024-05-01T12:00:00Z"
+}
+`))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}, endpoint: "https://api.domain.com"}
+
+	m, err := adapter.ReadMetric(context.TODO(), "metric", eval.Context{
+		"account_id": "account",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Value != 42 {
+		t.Fatalf("expected metric value to be 42, got %d", m.Value)
+	}
+
+	if !m.UpdatedAt.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected metric updated at to be 2024-05-01T12:00:00Z, got %s", m.UpdatedAt)
+	}
+}
+
+func TestMetricValueNotFound(t *testing.T) {
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"error_code": "METRIC_NOT_FOUND", "key": "metric"}`))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}}
+
+	_, err := adapter.ReadMetric(context.TODO(), "metric", nil)
+	if !errors.Is(err, ErrMetricNotFound) {
+		t.Fatalf("expected error to be ErrMetricNotFound, got %v", err)
+	}
+}
+
+func TestResolveFeatureInt64(t *testing.T) {
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"error_code": "", "key": "flag", "value": 25}`))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}}
+
+	result, err := adapter.Int64Evaluation(context.TODO(), "flag", 0, nil)
+	if err != nil {
+		t.Fatalf("failed to resolve feature: %v", err)
+	}
+
+	if result != 25 {
+		t.Fatalf("expected result to be 25, got %d", result)
+	}
+}
+
+func TestMetricUpdateOptions(t *testing.T) {
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+
+		if body["occurred_at"] != "2024-05-01T12:00:00Z" {
+			t.Fatalf("expected request occurred_at to be 2024-05-01T12:00:00Z, got %v", body["occurred_at"])
+		}
+
+		if body["unit"] != "bytes" {
+			t.Fatalf("expected request unit to be bytes, got %v", body["unit"])
+		}
+
+		if attributes, ok := body["attributes"].(map[string]interface{}); !ok || attributes["region"] != "eu" {
+			t.Fatalf("expected request attributes region to be eu, got %v", body["attributes"])
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Body:       io.NopCloser(bytes.NewReader([]byte(``))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}, endpoint: "https://api.domain.com"}
+
+	ctx := WithMetricOptions(context.TODO(), NewMetricOptions(
+		WithOccurredAt(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		WithUnit("bytes"),
+		WithAttributes(map[string]interface{}{"region": "eu"}),
+	))
+	if err := adapter.IncMetric(ctx, "storage", 1024, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetricUpdateWithoutOptions(t *testing.T) {
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+
+		for _, key := range []string{"occurred_at", "unit", "attributes", "idempotency_key"} {
+			if _, ok := body[key]; ok {
+				t.Fatalf("expected request to omit %s, got %v", key, body[key])
+			}
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Body:       io.NopCloser(bytes.NewReader([]byte(``))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}, endpoint: "https://api.domain.com"}
+
+	if err := adapter.SetMetric(context.TODO(), "storage", 1024, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveFeatureRequireFresh(t *testing.T) {
+	var cacheControl string
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		cacheControl = req.Header.Get("Cache-Control")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"error_code": "", "key": "flag", "value": true}`))),
+		}, nil
+	}
+
+	adapter := Kickplan{client: &mockClient{}}
+
+	if _, err := adapter.BooleanEvaluation(context.TODO(), "flag", false, nil); err != nil || cacheControl != "" {
+		t.Fatalf("expected no cache control header, got %q, %v", cacheControl, err)
+	}
+
+	ctx := WithCallOptions(context.TODO(), CallOptions{RequireFresh: true})
+	if _, err := adapter.BooleanEvaluation(ctx, "flag", false, nil); err != nil || cacheControl != "no-cache" {
+		t.Fatalf("expected cache control header no-cache, got %q, %v", cacheControl, err)
+	}
+}
+
+// idleClosingClient is a mock HTTP client that records whether its idle
+// connections were closed.
+type idleClosingClient struct {
+	mockClient
+
+	closed bool
+}
+
+func (c *idleClosingClient) CloseIdleConnections() {
+	c.closed = true
+}
+
+func TestKickplanShutdown(t *testing.T) {
+	client := &idleClosingClient{}
+	k := NewKickplan("", "token", "", "", WithHTTPClient(client))
+
+	if err := Shutdown(context.TODO(), k); err != nil || !client.closed {
+		t.Fatalf("expected idle connections to be closed, got %v", err)
+	}
+}
+
+func TestKickplanStatus(t *testing.T) {
+	var code int
+	DoFunc = func(req *http.Request) (*http.Response, error) {
+		if code == 0 {
+			return nil, errors.New("connection refused")
+		}
+
+		return &http.Response{
+			StatusCode: code,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`[]`))),
+		}, nil
+	}
+
+	k := NewKickplan("", "token", "", "", WithHTTPClient(&mockClient{}))
+	if s, _ := k.Status(); s != StatusNotReady {
+		t.Fatalf("expected status not ready, got %s", s)
+	}
+
+	for _, tc := range []struct {
+		code     int
+		expected Status
+	}{
+		{0, StatusError},
+		{http.StatusUnauthorized, StatusError},
+		{http.StatusOK, StatusReady},
+		{http.StatusNotFound, StatusReady},
+		{http.StatusBadGateway, StatusStale},
+		{0, StatusStale},
+		{http.StatusOK, StatusReady},
+	} {
+		code = tc.code
+		if err := Init(context.TODO(), k); err != nil {
+			t.Fatalf("expected init to succeed, got %v", err)
+		}
+
+		s, err := k.Status()
+		if s != tc.expected {
+			t.Fatalf("expected status %s after %d, got %s", tc.expected, tc.code, s)
+		}
+
+		if (err != nil) != (s == StatusError || s == StatusStale) {
+			t.Fatalf("unexpected error for status %s: %v", s, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	code = 0
+	_, _ = k.AllEvaluations(ctx, nil)
+
+	if s, _ := k.Status(); s != StatusReady {
+		t.Fatalf("expected canceled request to keep status ready, got %s", s)
+	}
+}
@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+)
+
+// Lifecycle is implemented by adapters that hold resources, e.g. background
+// goroutines, connections or buffered metric updates.
+//
+// Adapters that wrap another adapter only handle their own resources: Init
+// and Shutdown of this package look through wrappers and call the methods
+// of every adapter of the chain.
+type Lifecycle interface {
+	// Init prepares the adapter before it is used, e.g. loads its initial
+	// data or opens connections.
+	Init(ctx context.Context) error
+
+	// Shutdown releases the resources of the adapter. Pending work, like
+	// buffered metric updates, is completed until ctx is done.
+	Shutdown(ctx context.Context) error
+}
+
+// Init initializes a and the adapters it wraps, innermost first. It stops at
+// the first adapter that fails.
+func Init(ctx context.Context, a Adapter) error {
+	chain := unwrapAll(a)
+	for i := len(chain) - 1; i >= 0; i-- {
+		l, ok := chain[i].(Lifecycle)
+		if !ok {
+			continue
+		}
+
+		if err := l.Init(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown shuts down a and the adapters it wraps, outermost first, so that
+// wrappers can complete their work with the adapters they wrap. All adapters
+// are shut down even if some fail.
+func Shutdown(ctx context.Context, a Adapter) error {
+	var errs []error
+	for _, a := range unwrapAll(a) {
+		if l, ok := a.(Lifecycle); ok {
+			errs = append(errs, l.Shutdown(ctx))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// unwrapAll returns a and the adapters it wraps, outermost first.
+func unwrapAll(a Adapter) []Adapter {
+	var chain []Adapter
+	for a != nil {
+		chain = append(chain, a)
+
+		w, ok := a.(Wrapper)
+		if !ok {
+			break
+		}
+		a = w.Unwrap()
+	}
+
+	return chain
+}
@@ -0,0 +1,122 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lifecycleAdapter is an adapter that records its lifecycle calls.
+type lifecycleAdapter struct {
+	*InMemory
+
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (a *lifecycleAdapter) Init(_ context.Context) error {
+	*a.calls = append(*a.calls, "init "+a.name)
+	return a.err
+}
+
+func (a *lifecycleAdapter) Shutdown(_ context.Context) error {
+	*a.calls = append(*a.calls, "shutdown "+a.name)
+	return a.err
+}
+
+// lifecycleWrapper is a wrapping adapter that records its lifecycle calls.
+type lifecycleWrapper struct {
+	lifecycleAdapter
+
+	next Adapter
+}
+
+func (w *lifecycleWrapper) Unwrap() Adapter {
+	return w.next
+}
+
+func TestLifecycle(t *testing.T) {
+	var calls []string
+	inner := &lifecycleAdapter{InMemory: NewInMemory(), name: "inner", calls: &calls}
+	outer := &lifecycleWrapper{
+		lifecycleAdapter: lifecycleAdapter{InMemory: NewInMemory(), name: "outer", calls: &calls},
+		next:             Chain(inner, WithCache(time.Minute)),
+	}
+
+	a := Chain(outer, WithTimeout(time.Second))
+
+	if err := Init(context.TODO(), a); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	if err := Shutdown(context.TODO(), a); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+
+	if s := strings.Join(calls, ", "); s != "init inner, init outer, shutdown outer, shutdown inner" {
+		t.Fatalf("unexpected calls %s", s)
+	}
+
+	calls = nil
+	inner.err = errUnavailable
+
+	if err := Init(context.TODO(), a); !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+
+	if err := Shutdown(context.TODO(), a); !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+
+	if s := strings.Join(calls, ", "); s != "init inner, shutdown outer, shutdown inner" {
+		t.Fatalf("unexpected calls %s", s)
+	}
+}
+
+func TestFallbackLifecycle(t *testing.T) {
+	var calls []string
+	f := NewFallback(
+		FallbackLink{Name: "primary", Adapter: &lifecycleAdapter{InMemory: NewInMemory(), name: "primary", calls: &calls}},
+		FallbackLink{Name: "defaults", Adapter: &lifecycleAdapter{InMemory: NewInMemory(), name: "defaults", calls: &calls}},
+	)
+
+	_ = Init(context.TODO(), f)
+	_ = Shutdown(context.TODO(), f)
+
+	if s := strings.Join(calls, ", "); s != "init primary, init defaults, shutdown primary, shutdown defaults" {
+		t.Fatalf("unexpected calls %s", s)
+	}
+}
+
+func TestShadowShutdown(t *testing.T) {
+	var calls []string
+	secondary := &lifecycleAdapter{InMemory: NewInMemory(), name: "shadow", calls: &calls}
+
+	release := make(chan struct{})
+	blocked := Chain(secondary, Intercept(func(ctx context.Context, _ *Call, next func(context.Context) error) error {
+		<-release
+		return next(ctx)
+	}))
+
+	s := NewShadow(NewInMemory(), blocked)
+	_, _ = s.BooleanEvaluation(context.TODO(), "a", false, nil)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while a comparison is pending, got %v", err)
+	}
+
+	close(release)
+	if err := s.Shutdown(context.TODO()); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+
+	if len(calls) != 2 || calls[1] != "shutdown shadow" {
+		t.Fatalf("expected the shadow adapter to be shut down, got %v", calls)
+	}
+}
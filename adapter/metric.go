@@ -0,0 +1,210 @@
+package adapter
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// MetricKind is the kind of a metric recorded with RecordMetric.
+type MetricKind string
+
+// Metric kinds.
+const (
+	// MetricKindCounter is a monotonic counter. Recorded values are added to
+	// it and must not be negative.
+	MetricKindCounter MetricKind = "counter"
+
+	// MetricKindGauge is a value that can go up and down. Recorded values
+	// replace it.
+	MetricKindGauge MetricKind = "gauge"
+
+	// MetricKindHistogram is a distribution of observations, e.g. request
+	// sizes or latencies.
+	MetricKindHistogram MetricKind = "histogram"
+)
+
+var (
+	// ErrMetricReadUnsupported is returned when an adapter cannot read the
+	// value of a metric.
+	ErrMetricReadUnsupported = fmt.Errorf("METRIC_READ_UNSUPPORTED")
+
+	// ErrMetricRecordUnsupported is returned when an adapter cannot record
+	// counters, gauges and histograms.
+	ErrMetricRecordUnsupported = fmt.Errorf("METRIC_RECORD_UNSUPPORTED")
+
+	// ErrNegativeCounter is returned when a negative value is added to a counter.
+	ErrNegativeCounter = fmt.Errorf("NEGATIVE_COUNTER")
+
+	// ErrInvalidMetricValue is returned when a metric value is not a finite number.
+	ErrInvalidMetricValue = fmt.Errorf("INVALID_METRIC_VALUE")
+
+	// ErrMetricKindMismatch is returned when a metric is recorded with a
+	// different kind than before.
+	ErrMetricKindMismatch = fmt.Errorf("METRIC_KIND_MISMATCH")
+)
+
+// MetricReader is implemented by adapters that can read the value of a
+// metric.
+type MetricReader interface {
+	// ReadMetric returns the value of a metric for an evaluation context or
+	// ErrMetricNotFound.
+	ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error)
+}
+
+// ReadMetric reads the value of a metric with a if it implements
+// MetricReader and returns ErrMetricReadUnsupported otherwise.
+func ReadMetric(ctx context.Context, a Adapter, metric string, evalCtx eval.Context) (Metric, error) {
+	r, ok := a.(MetricReader)
+	if !ok {
+		return Metric{}, ErrMetricReadUnsupported
+	}
+
+	return r.ReadMetric(ctx, metric, evalCtx)
+}
+
+// MetricRecorder is implemented by adapters that can record counters, gauges
+// and histograms.
+type MetricRecorder interface {
+	// RecordMetric records a value of a metric of the given kind. Like the
+	// other metric updates, it honors the MetricOptions carried by ctx.
+	RecordMetric(ctx context.Context, metric string, kind MetricKind, value float64, evalCtx eval.Context) error
+}
+
+// RecordMetric records a value of a metric with a if it implements
+// MetricRecorder and returns ErrMetricRecordUnsupported otherwise.
+func RecordMetric(
+	ctx context.Context,
+	a Adapter,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	r, ok := a.(MetricRecorder)
+	if !ok {
+		return ErrMetricRecordUnsupported
+	}
+
+	return r.RecordMetric(ctx, metric, kind, value, evalCtx)
+}
+
+// MetricKey returns the key that identifies a metric for an evaluation
+// context. Equal contexts have equal keys. It fails for contexts that cannot
+// be encoded as JSON.
+func MetricKey(metric string, evalCtx eval.Context) (string, error) {
+	c, err := encodeContext(evalCtx)
+	if err != nil {
+		return "", err
+	}
+
+	return metric + "\x00" + c, nil
+}
+
+// checkMetric validates a value recorded with RecordMetric.
+func checkMetric(kind MetricKind, value float64) error {
+	switch kind {
+	case MetricKindCounter, MetricKindGauge, MetricKindHistogram:
+	default:
+		return fmt.Errorf("unknown metric kind %q", kind)
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return ErrInvalidMetricValue
+	}
+
+	if kind == MetricKindCounter && value < 0 {
+		return ErrNegativeCounter
+	}
+
+	return nil
+}
+
+// MetricOptions holds the optional parameters of a metric update. Like
+// CallOptions, they travel with the context of the update, so that the
+// Adapter interface does not depend on them: see WithMetricOptions.
+type MetricOptions struct {
+	// IdempotencyKey identifies the update, so that retries of the same
+	// update are applied once.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// OccurredAt is when the measured usage happened. It defaults to the
+	// time the update is received.
+	OccurredAt time.Time `json:"occurred_at,omitzero"`
+
+	// Unit is the unit of the value, e.g. "bytes" or "seconds".
+	Unit string `json:"unit,omitempty"`
+
+	// Attributes describe the update, e.g. the region it happened in.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// MetricOption is a function that configures a metric update.
+type MetricOption func(*MetricOptions)
+
+// WithIdempotencyKey sets the idempotency key of a metric update.
+func WithIdempotencyKey(key string) MetricOption {
+	return func(o *MetricOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
+// WithOccurredAt sets when the usage reported by a metric update happened,
+// e.g. to report historical usage from a batch job.
+func WithOccurredAt(t time.Time) MetricOption {
+	return func(o *MetricOptions) {
+		o.OccurredAt = t
+	}
+}
+
+// WithUnit sets the unit of the value of a metric update.
+func WithUnit(unit string) MetricOption {
+	return func(o *MetricOptions) {
+		o.Unit = unit
+	}
+}
+
+// WithAttributes sets the attributes of a metric update.
+func WithAttributes(attributes map[string]interface{}) MetricOption {
+	return func(o *MetricOptions) {
+		o.Attributes = attributes
+	}
+}
+
+// NewMetricOptions returns the parameters configured by opt.
+func NewMetricOptions(opt ...MetricOption) MetricOptions {
+	var o MetricOptions
+	for _, apply := range opt {
+		apply(&o)
+	}
+
+	return o
+}
+
+type metricOptionsKey struct{}
+
+// WithMetricOptions returns a copy of ctx that carries o to the adapters.
+func WithMetricOptions(ctx context.Context, o MetricOptions) context.Context {
+	return context.WithValue(ctx, metricOptionsKey{}, o)
+}
+
+// MetricOptionsFromContext returns the metric options carried by ctx.
+func MetricOptionsFromContext(ctx context.Context) MetricOptions {
+	o, _ := ctx.Value(metricOptionsKey{}).(MetricOptions)
+	return o
+}
+
+// NewIdempotencyKey returns a random idempotency key in the UUID version 4 format.
+func NewIdempotencyKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,352 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Middleware wraps an adapter to add behavior to it, e.g. caching or logging.
+type Middleware func(Adapter) Adapter
+
+// Chain wraps base with the middlewares. The first middleware is the
+// outermost one, so it sees every call first:
+//
+//	adapter.Chain(base, adapter.WithLogging(logger), adapter.WithCache(time.Minute))
+//
+// logs every call, including the ones answered by the cache.
+func Chain(base Adapter, mw ...Middleware) Adapter {
+	a := base
+	for i := len(mw) - 1; i >= 0; i-- {
+		a = mw[i](a)
+	}
+
+	return a
+}
+
+// Call describes a call of an adapter passed to an Interceptor.
+type Call struct {
+	// Operation is one of the Operation constants.
+	Operation string
+
+	// Key is the flag or metric of the call.
+	Key string
+
+	EvalContext eval.Context
+
+	// Result is the value returned by the wrapped adapter. It is set when
+	// next returns.
+	Result interface{}
+}
+
+// Interceptor is called around every call of an adapter. It must call next
+// to call the wrapped adapter, possibly with a derived context, and return
+// its error, or return an error to fail the call without calling next.
+type Interceptor func(ctx context.Context, call *Call, next func(context.Context) error) error
+
+// Intercept returns a middleware that calls interceptor around every call,
+// e.g. to write an audit log.
+func Intercept(interceptor Interceptor) Middleware {
+	return func(next Adapter) Adapter {
+		return &intercepted{Adapter: next, interceptor: interceptor}
+	}
+}
+
+// WithTimeout returns a middleware that cancels calls that take longer than d.
+func WithTimeout(d time.Duration) Middleware {
+	return Intercept(func(ctx context.Context, _ *Call, next func(context.Context) error) error {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		return next(ctx)
+	})
+}
+
+// WithLogging returns a middleware that logs every call with its duration.
+// Successful calls are logged at debug level with their result, failed
+// calls at warn level. Tags of the call are logged, evaluation contexts are
+// not, since they may contain personal data.
+func WithLogging(logger *slog.Logger) Middleware {
+	return Intercept(func(ctx context.Context, call *Call, next func(context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+
+		attrs := []slog.Attr{
+			slog.String("operation", call.Operation),
+			slog.String("key", call.Key),
+			slog.Duration("duration", time.Since(start)),
+		}
+
+		if tags := CallOptionsFromContext(ctx).Tags; len(tags) > 0 {
+			attrs = append(attrs, slog.Any("tags", tags))
+		}
+
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelWarn, "kickplan call failed", append(attrs, slog.Any("error", err))...)
+			return err
+		}
+
+		logger.LogAttrs(ctx, slog.LevelDebug, "kickplan call", append(attrs, slog.Any("result", call.Result))...)
+		return nil
+	})
+}
+
+// maxRetryBackoff bounds the exponential backoff of WithRetry.
+const maxRetryBackoff = time.Minute
+
+// WithRetry returns a middleware that retries failed calls until they were
+// attempted attempts times, waiting backoff before the first retry and twice
+// as long before every further one, up to a minute. Calls that failed
+// because a flag or metric does not exist, a value is invalid, the operation
+// is not supported, the Kickplan API rejected the request or ctx is done are
+// not retried.
+//
+// Metric updates are retried too, so they should carry idempotency keys, as
+// updates made with a kickplan.Client do. Only the evaluation details of the
+// last attempt are reported. WithRetry panics if attempts is less than 1.
+func WithRetry(attempts int, backoff time.Duration) Middleware {
+	if attempts < 1 {
+		panic("adapter: retry with less than one attempt")
+	}
+
+	return Intercept(func(ctx context.Context, _ *Call, next func(context.Context) error) error {
+		var (
+			d   Details
+			err error
+		)
+		defer func() { ReportDetails(ctx, d) }()
+
+		delay := backoff
+		for i := range attempts {
+			if i > 0 {
+				select {
+				case <-time.After(delay):
+					if delay < maxRetryBackoff {
+						delay = min(delay*2, maxRetryBackoff)
+					}
+				case <-ctx.Done():
+					return err
+				}
+			}
+
+			// every attempt reports into its own details, so that the
+			// details of failed attempts do not leak into the result
+			d = Details{}
+			if err = next(WithDetails(ctx, &d)); err == nil || !retryable(ctx, err) {
+				return err
+			}
+		}
+
+		return err
+	})
+}
+
+// retryable reports whether a call that failed with err may succeed when it
+// is retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	for _, permanent := range []error{
+		ErrFlagNotFound,
+		ErrTypeMismatch,
+		ErrMetricNotFound,
+		ErrNegativeCounter,
+		ErrInvalidMetricValue,
+		ErrMetricKindMismatch,
+		ErrBulkEvaluationUnsupported,
+		ErrMetricReadUnsupported,
+		ErrMetricRecordUnsupported,
+	} {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Permanent() {
+		return false
+	}
+
+	return true
+}
+
+// Verify that intercepted implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder and Wrapper.
+var (
+	_ Adapter        = (*intercepted)(nil)
+	_ BulkEvaluator  = (*intercepted)(nil)
+	_ MetricReader   = (*intercepted)(nil)
+	_ MetricRecorder = (*intercepted)(nil)
+	_ Wrapper        = (*intercepted)(nil)
+)
+
+// intercepted is an adapter that calls an interceptor around every call of
+// the wrapped adapter.
+type intercepted struct {
+	Adapter
+
+	interceptor Interceptor
+}
+
+// Unwrap returns the wrapped adapter.
+func (i *intercepted) Unwrap() Adapter {
+	return i.Adapter
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (i *intercepted) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return intercept(ctx, i, OperationBoolean, flag, evalCtx, defaultValue, func(ctx context.Context) (bool, error) {
+		return i.Adapter.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// StringEvaluation returns the value of a string flag.
+func (i *intercepted) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return intercept(ctx, i, OperationString, flag, evalCtx, defaultValue, func(ctx context.Context) (string, error) {
+		return i.Adapter.StringEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (i *intercepted) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return intercept(ctx, i, OperationInt64, flag, evalCtx, defaultValue, func(ctx context.Context) (int64, error) {
+		return i.Adapter.Int64Evaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (i *intercepted) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return intercept(ctx, i, OperationObject, flag, evalCtx, defaultValue, func(ctx context.Context) (interface{}, error) {
+		return i.Adapter.ObjectEvaluation(ctx, flag, defaultValue, evalCtx)
+	})
+}
+
+// AllEvaluations evaluates all flags with the wrapped adapter.
+func (i *intercepted) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	return intercept(ctx, i, OperationAllEvaluations, "", evalCtx, nil, func(ctx context.Context) ([]FlagEvaluation, error) {
+		return AllEvaluations(ctx, i.Adapter, evalCtx)
+	})
+}
+
+// SetBoolean sets the value of a boolean flag.
+func (i *intercepted) SetBoolean(ctx context.Context, flag string, value bool) error {
+	return i.interceptUpdate(ctx, OperationSetBoolean, flag, nil, func(ctx context.Context) error {
+		return i.Adapter.SetBoolean(ctx, flag, value)
+	})
+}
+
+// SetMetric sets the value of a metric.
+func (i *intercepted) SetMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return i.interceptUpdate(ctx, OperationSetMetric, metric, evalCtx, func(ctx context.Context) error {
+		return i.Adapter.SetMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// IncMetric increments the value of a metric.
+func (i *intercepted) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return i.interceptUpdate(ctx, OperationIncMetric, metric, evalCtx, func(ctx context.Context) error {
+		return i.Adapter.IncMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// DecMetric decrements the value of a metric.
+func (i *intercepted) DecMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return i.interceptUpdate(ctx, OperationDecMetric, metric, evalCtx, func(ctx context.Context) error {
+		return i.Adapter.DecMetric(ctx, metric, value, evalCtx)
+	})
+}
+
+// RecordMetric records a value of a counter, gauge or histogram.
+func (i *intercepted) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return i.interceptUpdate(ctx, OperationRecordMetric, metric, evalCtx, func(ctx context.Context) error {
+		return RecordMetric(ctx, i.Adapter, metric, kind, value, evalCtx)
+	})
+}
+
+// ReadMetric returns the value of a metric.
+func (i *intercepted) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return intercept(ctx, i, OperationReadMetric, metric, evalCtx, Metric{}, func(ctx context.Context) (Metric, error) {
+		return ReadMetric(ctx, i.Adapter, metric, evalCtx)
+	})
+}
+
+func (i *intercepted) interceptUpdate(
+	ctx context.Context,
+	operation string,
+	key string,
+	evalCtx eval.Context,
+	do func(context.Context) error,
+) error {
+	return i.interceptor(ctx, &Call{Operation: operation, Key: key, EvalContext: evalCtx}, do)
+}
+
+// intercept calls do through the interceptor. It returns defaultValue if
+// the interceptor does not call do.
+func intercept[T any](
+	ctx context.Context,
+	i *intercepted,
+	operation string,
+	key string,
+	evalCtx eval.Context,
+	defaultValue T,
+	do func(context.Context) (T, error),
+) (T, error) {
+	call := &Call{Operation: operation, Key: key, EvalContext: evalCtx}
+
+	value := defaultValue
+	err := i.interceptor(ctx, call, func(ctx context.Context) error {
+		var err error
+		value, err = do(ctx)
+		call.Result = value
+
+		return err
+	})
+
+	return value, err
+}
@@ -0,0 +1,326 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// flakyAdapter is an adapter whose boolean evaluations fail a number of
+// times before they succeed.
+type flakyAdapter struct {
+	*InMemory
+
+	failures int
+	calls    int
+}
+
+func (a *flakyAdapter) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	a.calls++
+	if a.calls <= a.failures {
+		ReportDetails(ctx, Details{Reason: ReasonError, ErrorCode: "GENERAL"})
+		return defaultValue, errUnavailable
+	}
+
+	return a.InMemory.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return Intercept(func(ctx context.Context, call *Call, next func(context.Context) error) error {
+			order = append(order, name+" "+call.Operation+" "+call.Key)
+			return next(ctx)
+		})
+	}
+
+	base := NewInMemory()
+	base.Flags["a"] = InMemoryFlag{Value: true}
+
+	a := Chain(base, trace("outer"), trace("inner"))
+
+	var d Details
+	v, err := a.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, nil)
+	if err != nil || !v {
+		t.Fatalf("expected flag a to be true, got %v, %v", v, err)
+	}
+
+	if d.Reason != ReasonStatic {
+		t.Fatalf("expected reason STATIC, got %s", d.Reason)
+	}
+
+	if strings.Join(order, ", ") != "outer boolean a, inner boolean a" {
+		t.Fatalf("unexpected order %v", order)
+	}
+
+	if _, err := AllEvaluations(context.TODO(), a, nil); err != nil {
+		t.Fatalf("expected bulk evaluations to be forwarded, got %v", err)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	var calls []Call
+	errDenied := errors.New("denied")
+
+	base := NewInMemory()
+	base.Flags["a"] = InMemoryFlag{Value: "blue"}
+
+	a := Chain(base, Intercept(func(ctx context.Context, call *Call, next func(context.Context) error) error {
+		if call.Key == "denied" {
+			return errDenied
+		}
+
+		err := next(ctx)
+		calls = append(calls, *call)
+
+		return err
+	}))
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if s, err := a.StringEvaluation(context.TODO(), "a", "", evalCtx); err != nil || s != "blue" {
+		t.Fatalf("expected flag a to be blue, got %q, %v", s, err)
+	}
+
+	if err := a.IncMetric(context.TODO(), "seats", 1, evalCtx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if s, err := a.StringEvaluation(context.TODO(), "denied", "default", nil); !errors.Is(err, errDenied) || s != "default" {
+		t.Fatalf("expected default value and denied error, got %q, %v", s, err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+
+	if c := calls[0]; c.Operation != OperationString || c.Key != "a" || c.Result != "blue" || c.EvalContext["account_id"] != "account" {
+		t.Fatalf("unexpected call %+v", c)
+	}
+
+	if c := calls[1]; c.Operation != OperationIncMetric || c.Key != "seats" {
+		t.Fatalf("unexpected call %+v", c)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	a := Chain(NewInMemory(), WithTimeout(time.Millisecond), Intercept(
+		func(ctx context.Context, _ *Call, next func(context.Context) error) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	))
+
+	if _, err := a.BooleanEvaluation(context.TODO(), "a", false, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestWithLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	base := NewInMemory()
+	base.Flags["a"] = InMemoryFlag{Value: true}
+
+	a := Chain(base, WithLogging(logger))
+
+	_, _ = a.BooleanEvaluation(context.TODO(), "a", false, eval.Context{"email": "user@example.com"})
+	_, _ = ReadMetric(context.TODO(), a, "seats", nil)
+
+	out := buf.String()
+	for _, s := range []string{
+		"level=DEBUG msg=\"kickplan call\" operation=boolean key=a",
+		"result=true",
+		"level=WARN msg=\"kickplan call failed\" operation=read_metric key=seats",
+	} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected log to contain %q, got %s", s, out)
+		}
+	}
+
+	if strings.Contains(out, "user@example.com") {
+		t.Fatalf("expected log not to contain the evaluation context, got %s", out)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	base := &flakyAdapter{InMemory: NewInMemory(), failures: 2}
+	base.Flags["a"] = InMemoryFlag{Value: true}
+
+	a := Chain(base, WithRetry(3, time.Millisecond))
+
+	var d Details
+	if v, err := a.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, nil); err != nil || !v {
+		t.Fatalf("expected flag a to be true after retries, got %v, %v", v, err)
+	}
+
+	if d.Reason != ReasonStatic || d.ErrorCode != "" {
+		t.Fatalf("expected details of the last attempt only, got %+v", d)
+	}
+
+	if base.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", base.calls)
+	}
+
+	base.calls = 0
+	a = Chain(base, WithRetry(2, time.Millisecond))
+	if _, err := a.BooleanEvaluation(context.TODO(), "a", false, nil); !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+
+	calls := 0
+	a = Chain(NewInMemory(), WithRetry(3, time.Millisecond), Intercept(
+		func(ctx context.Context, _ *Call, next func(context.Context) error) error {
+			calls++
+			return next(ctx)
+		},
+	))
+
+	if _, err := ReadMetric(context.TODO(), a, "seats", nil); !errors.Is(err, ErrMetricNotFound) {
+		t.Fatalf("expected metric not found, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected not found not to be retried, got %d calls", calls)
+	}
+
+	calls = 0
+	a = Chain(NewInMemory(), WithRetry(3, time.Millisecond), Intercept(
+		func(context.Context, *Call, func(context.Context) error) error {
+			calls++
+			return &HTTPError{StatusCode: http.StatusBadRequest}
+		},
+	))
+
+	if err := a.IncMetric(context.TODO(), "seats", 1, nil); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected bad request not to be retried, got %d calls", calls)
+	}
+}
+
+func TestWithRetryInvalidAttempts(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected WithRetry to panic")
+		}
+	}()
+
+	WithRetry(0, time.Millisecond)
+}
+
+func TestWithCache(t *testing.T) {
+	base := NewInMemory()
+	base.Flags["a"] = InMemoryFlag{Value: true}
+
+	var hits, misses int
+	a := Chain(base, WithCache(time.Minute, WithCacheObserver(func(hit bool) {
+		if hit {
+			hits++
+		} else {
+			misses++
+		}
+	})))
+
+	evalCtx := eval.Context{"account_id": "account"}
+	for i := range 2 {
+		var d Details
+		v, err := a.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, evalCtx)
+		if err != nil || !v {
+			t.Fatalf("expected flag a to be true, got %v, %v", v, err)
+		}
+
+		expected := ReasonStatic
+		if i > 0 {
+			expected = ReasonCached
+		}
+
+		if d.Reason != expected {
+			t.Fatalf("expected reason %s of evaluation %d, got %s", expected, i, d.Reason)
+		}
+	}
+
+	if hits != 1 || misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %d and %d", hits, misses)
+	}
+
+	if err := a.SetBoolean(context.TODO(), "a", false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if v, _ := a.BooleanEvaluation(context.TODO(), "a", true, evalCtx); v {
+		t.Fatalf("expected SetBoolean to invalidate the cached value")
+	}
+
+	var d Details
+	_, _ = a.BooleanEvaluation(context.TODO(), "missing", false, nil)
+	_, _ = a.BooleanEvaluation(WithDetails(context.TODO(), &d), "missing", false, nil)
+	if d.Reason != ReasonDefault {
+		t.Fatalf("expected default values not to be cached, got reason %s", d.Reason)
+	}
+}
+
+func TestWithCacheMaxEntries(t *testing.T) {
+	base := NewInMemory()
+	base.Flags["a"] = InMemoryFlag{Value: true}
+	base.Flags["b"] = InMemoryFlag{Value: true}
+
+	a := Chain(base, WithCache(time.Minute, WithCacheMaxEntries(1)))
+	_, _ = a.BooleanEvaluation(context.TODO(), "a", false, nil)
+	_, _ = a.BooleanEvaluation(context.TODO(), "b", false, nil)
+
+	if n := len(a.(*cache).entries); n != 1 {
+		t.Fatalf("expected 1 entry, got %d", n)
+	}
+}
+
+// silentAdapter is an adapter whose boolean evaluations report no details.
+type silentAdapter struct {
+	*InMemory
+	calls int
+}
+
+func (a *silentAdapter) BooleanEvaluation(context.Context, string, bool, eval.Context) (bool, error) {
+	a.calls++
+	return true, nil
+}
+
+func TestWithCacheUncacheable(t *testing.T) {
+	base := &silentAdapter{InMemory: NewInMemory()}
+
+	a := Chain(base, WithCache(time.Minute))
+	for range 2 {
+		_, _ = a.BooleanEvaluation(context.TODO(), "a", false, nil)
+	}
+
+	if base.calls != 2 {
+		t.Fatalf("expected evaluations without a reason not to be cached, got %d calls", base.calls)
+	}
+
+	inMemory := NewInMemory()
+	inMemory.Flags["a"] = InMemoryFlag{Value: true}
+
+	var d Details
+	a = Chain(inMemory, WithCache(time.Minute, WithCacheMaxEntries(0)))
+	for range 2 {
+		_, _ = a.BooleanEvaluation(WithDetails(context.TODO(), &d), "a", false, nil)
+	}
+
+	if d.Reason != ReasonStatic {
+		t.Fatalf("expected a cache without entries to be disabled, got reason %s", d.Reason)
+	}
+}
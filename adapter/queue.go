@@ -0,0 +1,613 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+const (
+	// DefaultQueueMaxBytes is the default limit of the disk usage of a queue.
+	DefaultQueueMaxBytes = 64 << 20
+
+	// DefaultQueueRetryInterval is the default initial interval between
+	// attempts to deliver a queued metric update.
+	DefaultQueueRetryInterval = time.Second
+
+	// DefaultQueueMaxAttempts is the default number of attempts to deliver a
+	// queued metric update. With the default retry interval, updates are
+	// retried for about an hour and a half.
+	DefaultQueueMaxAttempts = 100
+
+	// maxQueueRetryInterval bounds the exponential backoff of deliveries.
+	maxQueueRetryInterval = time.Minute
+)
+
+// ErrQueueFull is returned when a metric update does not fit in the disk
+// usage limit of a queue.
+var ErrQueueFull = fmt.Errorf("QUEUE_FULL")
+
+// errUndeliverable is the cause of dropping queued metric updates that are
+// missing, cannot be decoded or have an unknown operation.
+var errUndeliverable = fmt.Errorf("undeliverable metric update")
+
+// Verify that Queue implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder, Wrapper and Lifecycle.
+var (
+	_ Adapter        = (*Queue)(nil)
+	_ BulkEvaluator  = (*Queue)(nil)
+	_ MetricReader   = (*Queue)(nil)
+	_ MetricRecorder = (*Queue)(nil)
+	_ Wrapper        = (*Queue)(nil)
+	_ Lifecycle      = (*Queue)(nil)
+)
+
+// queuedUpdate is a metric update persisted by Queue.
+type queuedUpdate struct {
+	Operation string        `json:"operation"`
+	Metric    string        `json:"metric"`
+	Value     int64         `json:"value"`
+	Kind      MetricKind    `json:"kind,omitempty"`
+	Recorded  float64       `json:"recorded,omitempty"`
+	Context   eval.Context  `json:"context,omitempty"`
+	Options   MetricOptions `json:"options"`
+}
+
+type queuedFile struct {
+	name string
+	size int64
+}
+
+// DroppedUpdate describes a queued metric update that was given up, see
+// OnDrop.
+type DroppedUpdate struct {
+	Operation string
+	Metric    string
+	Value     int64
+	Kind      MetricKind
+	Recorded  float64
+	Context   eval.Context
+	Options   MetricOptions
+
+	// Attempts is the number of failed attempts to deliver the update.
+	Attempts int
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// Queue is an adapter that persists metric updates to a directory before
+// delivering them to the wrapped adapter in the background, so that updates
+// survive restarts of the process.
+//
+// Metric updates return once they are written and synced to disk. Updates
+// are delivered in order and retried with exponential backoff. Updates that
+// fail permanently, e.g. because the API rejected them, or that still fail
+// after the maximum number of attempts are dropped and reported to OnDrop,
+// so that they do not block the updates behind them. Updates left in the
+// directory by a previous process are delivered when a new Queue is created
+// for it. Every update is given an idempotency key before
+// it is persisted, so an update delivered again after a crash is applied
+// once. Flag evaluations and metric reads are passed through.
+type Queue struct {
+	Adapter
+
+	dir           string
+	maxBytes      int64
+	retryInterval time.Duration
+	maxAttempts   int
+	onPending     func(n int)
+	onDrop        func(DroppedUpdate)
+
+	// ctx is the context of deliveries, canceled when Close gives up.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending []queuedFile
+	size    int64
+	seq     uint64
+	closed  bool
+
+	// writes tracks the updates being written, Close waits for them.
+	writes sync.WaitGroup
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	drained chan struct{}
+}
+
+// QueueOption is a function that configures a Queue.
+type QueueOption func(*Queue)
+
+// WithQueueMaxBytes sets the limit of the disk usage of the queue. Metric
+// updates that would exceed it fail with ErrQueueFull.
+func WithQueueMaxBytes(n int64) QueueOption {
+	return func(q *Queue) {
+		q.maxBytes = n
+	}
+}
+
+// WithQueueRetryInterval sets the initial interval between attempts to
+// deliver a metric update.
+func WithQueueRetryInterval(d time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.retryInterval = d
+	}
+}
+
+// WithQueueMaxAttempts sets the number of attempts to deliver a metric update
+// before it is dropped. Zero or less retries updates until they succeed or
+// fail permanently. Defaults to DefaultQueueMaxAttempts.
+func WithQueueMaxAttempts(n int) QueueOption {
+	return func(q *Queue) {
+		q.maxAttempts = n
+	}
+}
+
+// OnDrop sets a function that is called with every metric update that is
+// dropped, e.g. to log it or to store it for manual replay. fn is called from
+// the delivery goroutine and should not block.
+func OnDrop(fn func(DroppedUpdate)) QueueOption {
+	return func(q *Queue) {
+		q.onDrop = fn
+	}
+}
+
+// OnPendingChange sets a function that is called with the number of pending
+// metric updates whenever it changes. fn is called in the order of the
+// changes with the queue locked, so it must not block or call the queue.
+func OnPendingChange(fn func(n int)) QueueOption {
+	return func(q *Queue) {
+		q.onPending = fn
+	}
+}
+
+// NewQueue returns a new Queue that persists metric updates to dir and
+// delivers them to next. Updates already in dir are delivered first.
+func NewQueue(next Adapter, dir string, opt ...QueueOption) (*Queue, error) {
+	q := &Queue{
+		Adapter:       next,
+		dir:           dir,
+		maxBytes:      DefaultQueueMaxBytes,
+		retryInterval: DefaultQueueRetryInterval,
+		maxAttempts:   DefaultQueueMaxAttempts,
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		drained:       make(chan struct{}),
+	}
+
+	for _, o := range opt {
+		o(q)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	go q.run()
+
+	return q, nil
+}
+
+// Unwrap returns the wrapped adapter.
+func (q *Queue) Unwrap() Adapter {
+	return q.Adapter
+}
+
+// Len returns the number of pending metric updates.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+// Flush waits until all pending metric updates are delivered or ctx is done.
+func (q *Queue) Flush(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		empty := len(q.pending) == 0
+		drained := q.drained
+		q.mu.Unlock()
+
+		if empty {
+			return nil
+		}
+
+		select {
+		case <-drained:
+		case <-q.stopped:
+			return fmt.Errorf("queue is closed with %d pending updates", q.Len())
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close delivers pending metric updates until ctx is done and stops the
+// queue. A delivery in progress when ctx is done is canceled. Updates that
+// were not delivered stay in the directory.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.writes.Wait()
+	err := q.Flush(ctx)
+
+	q.cancel()
+	close(q.done)
+	<-q.stopped
+
+	return err
+}
+
+// Init does nothing, the queue is started by NewQueue.
+func (q *Queue) Init(_ context.Context) error {
+	return nil
+}
+
+// Shutdown closes the queue, see Close.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	return q.Close(ctx)
+}
+
+// AllEvaluations evaluates all flags with the wrapped adapter.
+func (q *Queue) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	return AllEvaluations(ctx, q.Adapter, evalCtx)
+}
+
+// ReadMetric returns the value of a metric from the wrapped adapter.
+func (q *Queue) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return ReadMetric(ctx, q.Adapter, metric, evalCtx)
+}
+
+// SetMetric queues setting the value of a metric.
+func (q *Queue) SetMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return q.enqueue(queuedUpdate{
+		Operation: OperationSetMetric,
+		Metric:    metric,
+		Value:     value,
+		Context:   evalCtx,
+		Options:   MetricOptionsFromContext(ctx),
+	})
+}
+
+// IncMetric queues incrementing the value of a metric.
+func (q *Queue) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return q.enqueue(queuedUpdate{
+		Operation: OperationIncMetric,
+		Metric:    metric,
+		Value:     value,
+		Context:   evalCtx,
+		Options:   MetricOptionsFromContext(ctx),
+	})
+}
+
+// DecMetric queues decrementing the value of a metric.
+func (q *Queue) DecMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	return q.enqueue(queuedUpdate{
+		Operation: OperationDecMetric,
+		Metric:    metric,
+		Value:     value,
+		Context:   evalCtx,
+		Options:   MetricOptionsFromContext(ctx),
+	})
+}
+
+// RecordMetric queues recording a value of a counter, gauge or histogram.
+func (q *Queue) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	// invalid values would never be delivered, so reject them right away
+	if err := checkMetric(kind, value); err != nil {
+		return err
+	}
+
+	if _, ok := q.Adapter.(MetricRecorder); !ok {
+		return ErrMetricRecordUnsupported
+	}
+
+	return q.enqueue(queuedUpdate{
+		Operation: OperationRecordMetric,
+		Metric:    metric,
+		Kind:      kind,
+		Recorded:  value,
+		Context:   evalCtx,
+		Options:   MetricOptionsFromContext(ctx),
+	})
+}
+
+func (q *Queue) enqueue(u queuedUpdate) error {
+	if u.Options.IdempotencyKey == "" {
+		u.Options.IdempotencyKey = NewIdempotencyKey()
+	}
+
+	b, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("failed to encode metric update: %w", err)
+	}
+
+	// reserve the space and a name, then write the file without holding the
+	// lock so that a slow disk does not block deliveries
+	size := int64(len(b))
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return fmt.Errorf("queue is closed")
+	}
+
+	if q.size+size > q.maxBytes {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	q.seq++
+	name := fmt.Sprintf("%020d-%010d.json", time.Now().UnixNano(), q.seq)
+	q.size += size
+	q.writes.Add(1)
+	q.mu.Unlock()
+
+	defer q.writes.Done()
+
+	if err := writeFileSync(filepath.Join(q.dir, name), b); err != nil {
+		q.mu.Lock()
+		q.size -= size
+		q.mu.Unlock()
+		return fmt.Errorf("failed to write metric update: %w", err)
+	}
+
+	q.mu.Lock()
+	q.pending = append(q.pending, queuedFile{name: name, size: size})
+	q.notify(len(q.pending))
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *Queue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read queue directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			_ = os.Remove(filepath.Join(q.dir, e.Name()))
+			continue
+		}
+
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read queued metric update: %w", err)
+		}
+
+		q.pending = append(q.pending, queuedFile{name: e.Name(), size: info.Size()})
+		q.size += info.Size()
+	}
+
+	sort.Slice(q.pending, func(i, j int) bool {
+		return q.pending[i].name < q.pending[j].name
+	})
+
+	return nil
+}
+
+func (q *Queue) run() {
+	defer close(q.stopped)
+
+	backoff := q.retryInterval
+	attempts := 0
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			close(q.drained)
+			q.drained = make(chan struct{})
+			q.mu.Unlock()
+
+			select {
+			case <-q.wake:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+		f := q.pending[0]
+		q.mu.Unlock()
+
+		attempts++
+		if u, err := q.deliver(f); err != nil {
+			if q.ctx.Err() != nil {
+				// Close gave up, leave the update for the next process
+				return
+			}
+
+			if retryable(q.ctx, err) && !errors.Is(err, errUndeliverable) &&
+				(q.maxAttempts <= 0 || attempts < q.maxAttempts) {
+				log.Printf("WARN failed to deliver queued metric update %s: %v", f.name, err)
+
+				select {
+				case <-time.After(backoff):
+					backoff = min(backoff*2, maxQueueRetryInterval)
+					continue
+				case <-q.done:
+					return
+				}
+			}
+
+			log.Printf("WARN dropping queued metric update %s after %d attempts: %v", f.name, attempts, err)
+			if err := os.Remove(filepath.Join(q.dir, f.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("WARN failed to remove queued metric update %s: %v", f.name, err)
+			}
+
+			if q.onDrop != nil {
+				q.onDrop(DroppedUpdate{
+					Operation: u.Operation,
+					Metric:    u.Metric,
+					Value:     u.Value,
+					Kind:      u.Kind,
+					Recorded:  u.Recorded,
+					Context:   u.Context,
+					Options:   u.Options,
+					Attempts:  attempts,
+					Err:       err,
+				})
+			}
+		}
+		backoff = q.retryInterval
+		attempts = 0
+
+		q.mu.Lock()
+		q.pending = q.pending[1:]
+		q.size -= f.size
+		q.notify(len(q.pending))
+		q.mu.Unlock()
+	}
+}
+
+// deliver delivers a queued update to the wrapped adapter and removes it. It
+// returns the update, as far as it could be decoded, for OnDrop.
+func (q *Queue) deliver(f queuedFile) (queuedUpdate, error) {
+	path := filepath.Join(q.dir, f.name)
+
+	var u queuedUpdate
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the file was removed from the directory, there is nothing to retry
+		return u, fmt.Errorf("%w: %w", errUndeliverable, err)
+	}
+	if err != nil {
+		return u, err
+	}
+
+	if err := json.Unmarshal(b, &u); err != nil {
+		return u, fmt.Errorf("%w: %w", errUndeliverable, err)
+	}
+
+	ctx := WithMetricOptions(q.ctx, u.Options)
+	switch u.Operation {
+	case OperationSetMetric:
+		err = q.Adapter.SetMetric(ctx, u.Metric, u.Value, u.Context)
+	case OperationIncMetric:
+		err = q.Adapter.IncMetric(ctx, u.Metric, u.Value, u.Context)
+	case OperationDecMetric:
+		err = q.Adapter.DecMetric(ctx, u.Metric, u.Value, u.Context)
+	case OperationRecordMetric:
+		err = RecordMetric(ctx, q.Adapter, u.Metric, u.Kind, u.Recorded, u.Context)
+	default:
+		err = fmt.Errorf("%w: unknown operation %q", errUndeliverable, u.Operation)
+	}
+
+	if err != nil {
+		return u, err
+	}
+
+	return u, os.Remove(path)
+}
+
+// writeFileSync writes b to path atomically and durably: it writes a
+// temporary file, syncs it and renames it to path, so that a crash never
+// leaves a partial file behind, and syncs the directory, so that the rename
+// survives a crash too.
+func writeFileSync(path string, b []byte) error {
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+
+	return dir.Sync()
+}
+
+// notify reports the number of pending updates to OnPendingChange. It must be
+// called with q.mu held, so that the reports are not reordered.
+func (q *Queue) notify(n int) {
+	if q.onPending != nil {
+		q.onPending(n)
+	}
+}
@@ -0,0 +1,337 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+type failingAdapter struct {
+	*InMemory
+	fail atomic.Bool
+}
+
+func (a *failingAdapter) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	if a.fail.Load() {
+		return errors.New("unavailable")
+	}
+
+	return a.InMemory.IncMetric(ctx, metric, value, evalCtx)
+}
+
+func TestQueue(t *testing.T) {
+	inMemory := NewInMemory()
+
+	var (
+		mu      sync.Mutex
+		pending []int
+	)
+	queue, err := NewQueue(inMemory, t.TempDir(), OnPendingChange(func(n int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		pending = append(pending, n)
+	}))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if err := queue.SetMetric(context.TODO(), "seats", 10, evalCtx); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 5, evalCtx); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if err := queue.DecMetric(context.TODO(), "seats", 3, evalCtx); err != nil {
+		t.Fatalf("failed to decrement metric: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	if v := inMemory.GetMetric("seats", evalCtx); v != 12 {
+		t.Fatalf("expected metric seats to be 12, got %d", v)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// every update is reported once queued and once delivered
+	if len(pending) != 6 || pending[len(pending)-1] != 0 {
+		t.Fatalf("expected 6 pending counts ending with 0, got %v", pending)
+	}
+}
+
+func TestQueueReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	failing := &failingAdapter{InMemory: NewInMemory()}
+	failing.fail.Store(true)
+
+	queue, err := NewQueue(failing, dir, WithQueueRetryInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if err := queue.IncMetric(withIdempotencyKey("key"), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 2, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := queue.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to be context.DeadlineExceeded, got %v", err)
+	}
+
+	inMemory := NewInMemory()
+	queue, err = NewQueue(inMemory, dir)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if queue.Len() != 2 {
+		t.Fatalf("expected 2 pending updates, got %d", queue.Len())
+	}
+
+	ctx, cancel = context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != 3 {
+		t.Fatalf("expected metric seats to be 3, got %d", v)
+	}
+
+	updates := inMemory.MetricUpdates()
+	if len(updates) != 2 || updates[0].IdempotencyKey != "key" || updates[1].IdempotencyKey == "" {
+		t.Fatalf("expected updates to keep their idempotency keys, got %+v", updates)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	failing := &failingAdapter{InMemory: NewInMemory()}
+	failing.fail.Store(true)
+
+	queue, err := NewQueue(failing, t.TempDir(), WithQueueMaxBytes(300))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+		defer cancel()
+		_ = queue.Close(ctx)
+	}()
+
+	var lastErr error
+	for range 10 {
+		if lastErr = queue.IncMetric(context.TODO(), "seats", 1, nil); lastErr != nil {
+			break
+		}
+	}
+
+	if !errors.Is(lastErr, ErrQueueFull) {
+		t.Fatalf("expected error to be ErrQueueFull, got %v", lastErr)
+	}
+}
+
+// rejectingAdapter is an adapter that rejects updates of the rejected metric
+// like the Kickplan API rejects invalid requests.
+type rejectingAdapter struct {
+	*InMemory
+}
+
+func (a *rejectingAdapter) IncMetric(
+	ctx context.Context,
+	metric string,
+	value int64,
+	evalCtx eval.Context,
+) error {
+	if metric == "rejected" {
+		return &HTTPError{StatusCode: http.StatusBadRequest}
+	}
+
+	return a.InMemory.IncMetric(ctx, metric, value, evalCtx)
+}
+
+func TestQueueDropPermanentFailure(t *testing.T) {
+	inMemory := NewInMemory()
+
+	dropped := make(chan DroppedUpdate, 1)
+	queue, err := NewQueue(&rejectingAdapter{InMemory: inMemory}, t.TempDir(), OnDrop(func(u DroppedUpdate) {
+		dropped <- u
+	}))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	// fails with a client error, which is not retried
+	if err := queue.IncMetric(context.TODO(), "rejected", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	select {
+	case u := <-dropped:
+		var httpErr *HTTPError
+		if u.Metric != "rejected" || u.Attempts != 1 || !errors.As(u.Err, &httpErr) {
+			t.Fatalf("unexpected dropped update %+v", u)
+		}
+	default:
+		t.Fatalf("expected an update to be dropped")
+	}
+
+	if v := inMemory.GetMetric("seats", nil); v != 1 {
+		t.Fatalf("expected metric seats to be 1, got %d", v)
+	}
+}
+
+func TestQueueMaxAttempts(t *testing.T) {
+	failing := &failingAdapter{InMemory: NewInMemory()}
+	failing.fail.Store(true)
+
+	dropped := make(chan DroppedUpdate, 1)
+	queue, err := NewQueue(failing, t.TempDir(),
+		WithQueueRetryInterval(time.Millisecond),
+		WithQueueMaxAttempts(3),
+		OnDrop(func(u DroppedUpdate) {
+			dropped <- u
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	if u := <-dropped; u.Attempts != 3 || u.Err == nil {
+		t.Fatalf("expected update to be dropped after 3 attempts, got %+v", u)
+	}
+}
+
+func TestQueueDropMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	failing := &failingAdapter{InMemory: NewInMemory()}
+	failing.fail.Store(true)
+
+	dropped := make(chan DroppedUpdate, 1)
+	queue, err := NewQueue(failing, dir,
+		WithQueueRetryInterval(time.Millisecond),
+		WithQueueMaxAttempts(0),
+		OnDrop(func(u DroppedUpdate) {
+			dropped <- u
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 queued file, got %v: %v", files, err)
+	}
+
+	if err := os.Remove(files[0]); err != nil {
+		t.Fatalf("failed to remove queued file: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	if u := <-dropped; !errors.Is(u.Err, fs.ErrNotExist) {
+		t.Fatalf("expected missing update to be dropped, got %+v", u)
+	}
+}
+
+type blockingAdapter struct {
+	*InMemory
+}
+
+func (a *blockingAdapter) IncMetric(ctx context.Context, _ string, _ int64, _ eval.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestQueueCloseCancelsDelivery(t *testing.T) {
+	dir := t.TempDir()
+
+	queue, err := NewQueue(&blockingAdapter{InMemory: NewInMemory()}, dir)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	if err := queue.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := queue.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to be context.DeadlineExceeded, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read queue directory: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected the canceled update to stay queued, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,367 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+const (
+	// DefaultShadowTimeout is the default timeout of shadow evaluations.
+	DefaultShadowTimeout = 5 * time.Second
+
+	// DefaultShadowMaxInFlight is the default maximum number of concurrent
+	// shadow evaluations.
+	DefaultShadowMaxInFlight = 100
+)
+
+// Verify that Shadow implements Adapter, BulkEvaluator, MetricReader,
+// MetricRecorder, Wrapper and Lifecycle.
+var (
+	_ Adapter        = (*Shadow)(nil)
+	_ BulkEvaluator  = (*Shadow)(nil)
+	_ MetricReader   = (*Shadow)(nil)
+	_ MetricRecorder = (*Shadow)(nil)
+	_ Wrapper        = (*Shadow)(nil)
+	_ Lifecycle      = (*Shadow)(nil)
+)
+
+// Mismatch describes a flag that a Shadow adapter evaluated differently with
+// its primary and shadow adapters.
+type Mismatch struct {
+	Flag        string
+	EvalContext eval.Context
+
+	Primary      interface{}
+	PrimaryError error
+	Shadow       interface{}
+	ShadowError  error
+}
+
+// Shadow is an adapter that evaluates flags with a primary and a shadow
+// adapter concurrently and returns the result of the primary, e.g. to
+// compare a new flag system with the one it replaces before switching.
+//
+// The results are compared in the background: they match when both
+// evaluations fail or both succeed with equal values; a panic of the shadow
+// adapter counts as a failed evaluation. Shadow evaluations do not delay
+// the primary and are not canceled with the context of the call. When the
+// maximum number of shadow evaluations is in flight, e.g. because the shadow
+// adapter is slow, further comparisons are skipped. Metric operations and
+// bulk evaluations only go to the primary.
+type Shadow struct {
+	Adapter
+
+	shadow     Adapter
+	sampleRate float64
+	timeout    time.Duration
+	onCompare  func(flag string, match bool)
+	onMismatch func(ctx context.Context, m Mismatch)
+	onSkip     func(flag string)
+
+	maxInFlight int
+	inFlight    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// ShadowOption is a function that configures a Shadow adapter.
+type ShadowOption func(*Shadow)
+
+// WithSampleRate sets the fraction of evaluations, from 0 to 1, that are
+// also made with the shadow adapter. Defaults to 1.
+func WithSampleRate(rate float64) ShadowOption {
+	return func(s *Shadow) {
+		s.sampleRate = rate
+	}
+}
+
+// WithShadowTimeout sets the timeout of shadow evaluations.
+func WithShadowTimeout(d time.Duration) ShadowOption {
+	return func(s *Shadow) {
+		s.timeout = d
+	}
+}
+
+// WithShadowMaxInFlight sets the maximum number of concurrent shadow
+// evaluations. Zero or less removes the limit. Defaults to
+// DefaultShadowMaxInFlight.
+func WithShadowMaxInFlight(n int) ShadowOption {
+	return func(s *Shadow) {
+		s.maxInFlight = n
+	}
+}
+
+// OnCompare sets a function that is called after every comparison, e.g. to
+// count matches and mismatches.
+func OnCompare(fn func(flag string, match bool)) ShadowOption {
+	return func(s *Shadow) {
+		s.onCompare = fn
+	}
+}
+
+// OnMismatch sets a function that is called with every mismatch. The
+// context carries the values of the context of the evaluation.
+func OnMismatch(fn func(ctx context.Context, m Mismatch)) ShadowOption {
+	return func(s *Shadow) {
+		s.onMismatch = fn
+	}
+}
+
+// OnSkip sets a function that is called with every sampled evaluation whose
+// comparison is skipped because the maximum number of shadow evaluations is
+// in flight.
+func OnSkip(fn func(flag string)) ShadowOption {
+	return func(s *Shadow) {
+		s.onSkip = fn
+	}
+}
+
+// NewShadow returns a new Shadow adapter.
+func NewShadow(primary, shadow Adapter, opt ...ShadowOption) *Shadow {
+	s := &Shadow{
+		Adapter:     primary,
+		shadow:      shadow,
+		sampleRate:  1,
+		timeout:     DefaultShadowTimeout,
+		maxInFlight: DefaultShadowMaxInFlight,
+	}
+
+	for _, o := range opt {
+		o(s)
+	}
+
+	if s.maxInFlight > 0 {
+		s.inFlight = make(chan struct{}, s.maxInFlight)
+	}
+
+	return s
+}
+
+// Wait waits until all pending comparisons are done.
+func (s *Shadow) Wait() {
+	s.wg.Wait()
+}
+
+// Unwrap returns the primary adapter.
+func (s *Shadow) Unwrap() Adapter {
+	return s.Adapter
+}
+
+// Init initializes the shadow adapter. The primary adapter is initialized
+// by the Init function of this package through Unwrap.
+func (s *Shadow) Init(ctx context.Context) error {
+	return Init(ctx, s.shadow)
+}
+
+// Shutdown waits until pending comparisons are done or ctx is done, and
+// shuts down the shadow adapter.
+func (s *Shadow) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to wait for shadow comparisons: %w", ctx.Err())
+	}
+
+	return errors.Join(err, Shutdown(ctx, s.shadow))
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (s *Shadow) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	return shadow(ctx, s, flag, defaultValue, evalCtx, Adapter.BooleanEvaluation)
+}
+
+// StringEvaluation returns the value of a string flag.
+func (s *Shadow) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	return shadow(ctx, s, flag, defaultValue, evalCtx, Adapter.StringEvaluation)
+}
+
+// Int64Evaluation returns the value of a int64 flag.
+func (s *Shadow) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	return shadow(ctx, s, flag, defaultValue, evalCtx, Adapter.Int64Evaluation)
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (s *Shadow) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	return shadow(ctx, s, flag, defaultValue, evalCtx, Adapter.ObjectEvaluation)
+}
+
+// AllEvaluations evaluates all flags with the primary adapter.
+func (s *Shadow) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]FlagEvaluation, error) {
+	return AllEvaluations(ctx, s.Adapter, evalCtx)
+}
+
+// RecordMetric records a value of a counter, gauge or histogram with the
+// primary adapter.
+func (s *Shadow) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return RecordMetric(ctx, s.Adapter, metric, kind, value, evalCtx)
+}
+
+// ReadMetric returns the value of a metric from the primary adapter.
+func (s *Shadow) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (Metric, error) {
+	return ReadMetric(ctx, s.Adapter, metric, evalCtx)
+}
+
+func (s *Shadow) sample() bool {
+	return s.sampleRate >= 1 || (s.sampleRate > 0 && rand.Float64() < s.sampleRate)
+}
+
+// acquire reserves a slot for a shadow evaluation. It reports false if all
+// slots are taken.
+func (s *Shadow) acquire(flag string) bool {
+	if s.inFlight == nil {
+		return true
+	}
+
+	select {
+	case s.inFlight <- struct{}{}:
+		return true
+	default:
+		if s.onSkip != nil {
+			s.onSkip(flag)
+		}
+
+		return false
+	}
+}
+
+func (s *Shadow) release() {
+	if s.inFlight != nil {
+		<-s.inFlight
+	}
+}
+
+func (s *Shadow) compare(ctx context.Context, m Mismatch) {
+	match := (m.PrimaryError != nil) == (m.ShadowError != nil) &&
+		(m.PrimaryError != nil || reflect.DeepEqual(m.Primary, m.Shadow))
+
+	if s.onCompare != nil {
+		s.onCompare(m.Flag, match)
+	}
+
+	if !match && s.onMismatch != nil {
+		s.onMismatch(ctx, m)
+	}
+}
+
+type shadowResult[T any] struct {
+	value T
+	err   error
+
+	// panicked is set when the primary evaluation panicked, so there is
+	// nothing to compare.
+	panicked bool
+}
+
+// shadow evaluates a flag with the primary adapter and, if sampled, with
+// the shadow adapter in the background.
+func shadow[T any](
+	ctx context.Context,
+	s *Shadow,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	evaluate func(Adapter, context.Context, string, T, eval.Context) (T, error),
+) (T, error) {
+	if !s.sample() || !s.acquire(flag) {
+		return evaluate(s.Adapter, ctx, flag, defaultValue, evalCtx)
+	}
+
+	primary := make(chan shadowResult[T], 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.release()
+
+		shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+		defer cancel()
+
+		value, err := evaluateShadow(shadowCtx, s.shadow, flag, defaultValue, evalCtx, evaluate)
+
+		p := <-primary
+		if p.panicked {
+			return
+		}
+
+		s.compare(shadowCtx, Mismatch{
+			Flag:         flag,
+			EvalContext:  evalCtx,
+			Primary:      p.value,
+			PrimaryError: p.err,
+			Shadow:       value,
+			ShadowError:  err,
+		})
+	}()
+
+	// send the result from a deferred call, so that the shadow goroutine
+	// and Wait do not hang if the primary panics
+	p := shadowResult[T]{panicked: true}
+	defer func() { primary <- p }()
+
+	value, err := evaluate(s.Adapter, ctx, flag, defaultValue, evalCtx)
+	p = shadowResult[T]{value: value, err: err}
+
+	return value, err
+}
+
+// evaluateShadow evaluates a flag with the shadow adapter. A panic of the
+// shadow adapter is returned as an error, so that it is compared like a
+// failed evaluation and does not crash the process.
+func evaluateShadow[T any](
+	ctx context.Context,
+	a Adapter,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	evaluate func(Adapter, context.Context, string, T, eval.Context) (T, error),
+) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = defaultValue, fmt.Errorf("shadow adapter panicked: %v", r)
+		}
+	}()
+
+	// the shadow reports into its own details, so that it does not
+	// overwrite the details of the primary
+	var d Details
+	return evaluate(a, WithDetails(ctx, &d), flag, defaultValue, evalCtx)
+}
@@ -0,0 +1,217 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func TestShadow(t *testing.T) {
+	primary := NewInMemory()
+	primary.Flags["same"] = InMemoryFlag{Value: true}
+	primary.Flags["different"] = InMemoryFlag{Value: "blue"}
+
+	secondary := NewInMemory()
+	secondary.Flags["same"] = InMemoryFlag{Value: true}
+	secondary.Flags["different"] = InMemoryFlag{Value: "green"}
+
+	var (
+		mu         sync.Mutex
+		compared   = make(map[string]bool)
+		mismatches []Mismatch
+	)
+
+	s := NewShadow(primary, secondary,
+		OnCompare(func(flag string, match bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			compared[flag] = match
+		}),
+		OnMismatch(func(_ context.Context, m Mismatch) {
+			mu.Lock()
+			defer mu.Unlock()
+			mismatches = append(mismatches, m)
+		}),
+	)
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if b, err := s.BooleanEvaluation(context.TODO(), "same", false, evalCtx); err != nil || !b {
+		t.Fatalf("expected flag same to be true, got %v, %v", b, err)
+	}
+
+	var d Details
+	str, err := s.StringEvaluation(WithDetails(context.TODO(), &d), "different", "", evalCtx)
+	if err != nil || str != "blue" {
+		t.Fatalf("expected flag different to be the primary value blue, got %q, %v", str, err)
+	}
+
+	s.Wait()
+
+	if d.Reason != ReasonStatic {
+		t.Fatalf("expected reason of the primary STATIC, got %s", d.Reason)
+	}
+
+	if match, ok := compared["same"]; !ok || !match {
+		t.Fatalf("expected flag same to match")
+	}
+
+	if match, ok := compared["different"]; !ok || match {
+		t.Fatalf("expected flag different to mismatch")
+	}
+
+	if len(mismatches) != 1 {
+		t.Fatalf("expected 1 mismatch, got %d", len(mismatches))
+	}
+
+	m := mismatches[0]
+	if m.Flag != "different" || m.Primary != "blue" || m.Shadow != "green" || m.EvalContext["account_id"] != "account" {
+		t.Fatalf("unexpected mismatch %+v", m)
+	}
+}
+
+func TestShadowSampling(t *testing.T) {
+	secondary := NewInMemory()
+
+	compared := 0
+	s := NewShadow(NewInMemory(), secondary,
+		WithSampleRate(0),
+		OnCompare(func(string, bool) { compared++ }),
+	)
+
+	for range 10 {
+		if _, err := s.BooleanEvaluation(context.TODO(), "flag", false, nil); err != nil {
+			t.Fatalf("failed to evaluate flag: %v", err)
+		}
+	}
+
+	if err := s.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	s.Wait()
+
+	if compared != 0 {
+		t.Fatalf("expected no comparisons, got %d", compared)
+	}
+
+	if updates := secondary.MetricUpdates(); len(updates) != 0 {
+		t.Fatalf("expected no metric updates of the shadow, got %d", len(updates))
+	}
+}
+
+// blockingShadow is an adapter whose boolean evaluations block until
+// release is closed.
+type blockingShadow struct {
+	*InMemory
+	release chan struct{}
+}
+
+func (a *blockingShadow) BooleanEvaluation(
+	_ context.Context,
+	_ string,
+	defaultValue bool,
+	_ eval.Context,
+) (bool, error) {
+	<-a.release
+	return defaultValue, nil
+}
+
+func TestShadowMaxInFlight(t *testing.T) {
+	secondary := &blockingShadow{InMemory: NewInMemory(), release: make(chan struct{})}
+
+	var skipped atomic.Int64
+	s := NewShadow(NewInMemory(), secondary,
+		WithShadowMaxInFlight(1),
+		OnSkip(func(string) { skipped.Add(1) }),
+	)
+
+	for range 3 {
+		if _, err := s.BooleanEvaluation(context.TODO(), "a", false, nil); err != nil {
+			t.Fatalf("failed to evaluate flag: %v", err)
+		}
+	}
+
+	close(secondary.release)
+	s.Wait()
+
+	if n := skipped.Load(); n != 2 {
+		t.Fatalf("expected 2 skipped comparisons, got %d", n)
+	}
+}
+
+// panickingAdapter is an adapter whose boolean evaluations panic.
+type panickingAdapter struct {
+	*InMemory
+}
+
+func (a *panickingAdapter) BooleanEvaluation(context.Context, string, bool, eval.Context) (bool, error) {
+	panic("boom")
+}
+
+func TestShadowPrimaryPanic(t *testing.T) {
+	compared := make(chan struct{}, 1)
+	s := NewShadow(&panickingAdapter{InMemory: NewInMemory()}, NewInMemory(),
+		OnCompare(func(string, bool) { compared <- struct{}{} }),
+	)
+
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = s.BooleanEvaluation(context.TODO(), "a", false, nil)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected Wait to return after the primary panicked")
+	}
+
+	if len(compared) != 0 {
+		t.Fatalf("expected no comparison after the primary panicked")
+	}
+}
+
+func TestShadowPanic(t *testing.T) {
+	var mismatch Mismatch
+	s := NewShadow(NewInMemory(), &panickingAdapter{InMemory: NewInMemory()},
+		WithShadowMaxInFlight(1),
+		OnMismatch(func(_ context.Context, m Mismatch) { mismatch = m }),
+	)
+
+	if _, err := s.BooleanEvaluation(context.TODO(), "a", false, nil); err != nil {
+		t.Fatalf("failed to evaluate flag: %v", err)
+	}
+	s.Wait()
+
+	if mismatch.ShadowError == nil || !strings.Contains(mismatch.ShadowError.Error(), "boom") {
+		t.Fatalf("expected the shadow panic to be reported as an error, got %+v", mismatch)
+	}
+
+	// the slot of the panicked evaluation is released
+	var skipped atomic.Int64
+	s = NewShadow(NewInMemory(), &panickingAdapter{InMemory: NewInMemory()},
+		WithShadowMaxInFlight(1),
+		OnSkip(func(string) { skipped.Add(1) }),
+	)
+
+	for range 3 {
+		if _, err := s.BooleanEvaluation(context.TODO(), "a", false, nil); err != nil {
+			t.Fatalf("failed to evaluate flag: %v", err)
+		}
+		s.Wait()
+	}
+
+	if n := skipped.Load(); n != 0 {
+		t.Fatalf("expected no skipped comparisons, got %d", n)
+	}
+}
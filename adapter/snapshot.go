@@ -0,0 +1,171 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Snapshot formats.
+const (
+	SnapshotFormatJSON = "json"
+	SnapshotFormatYAML = "yaml"
+)
+
+// Snapshot is the resolved state of flags for a list of evaluation contexts.
+type Snapshot struct {
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry holds the values of flags resolved for an evaluation
+// context. An entry without a context holds the values for any context.
+type SnapshotEntry struct {
+	Context eval.Context            `json:"context,omitempty"`
+	Flags   map[string]SnapshotFlag `json:"flags"`
+}
+
+// SnapshotFlag is the value of a flag in a snapshot.
+type SnapshotFlag struct {
+	Value   interface{} `json:"value"`
+	Variant string      `json:"variant,omitempty"`
+}
+
+// SnapshotFormatFromPath returns the format of a snapshot file based on its
+// extension. Files with the extensions .yaml and .yml are YAML, all other
+// files are JSON.
+func SnapshotFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return SnapshotFormatYAML
+	default:
+		return SnapshotFormatJSON
+	}
+}
+
+// ReadSnapshot decodes a snapshot in the given format from r.
+func ReadSnapshot(r io.Reader, format string) (Snapshot, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	switch format {
+	case SnapshotFormatJSON:
+	case SnapshotFormatYAML:
+		// convert YAML to JSON, so that values have the same types in both
+		// formats, e.g. numbers are float64
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+
+		if b, err = json.Marshal(v); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+	default:
+		return Snapshot{}, fmt.Errorf("unknown snapshot format %q", format)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	return s, nil
+}
+
+// WriteSnapshot encodes a snapshot in the given format to w.
+func WriteSnapshot(w io.Writer, s Snapshot, format string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	switch format {
+	case SnapshotFormatJSON:
+		b = append(b, '\n')
+	case SnapshotFormatYAML:
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+		b = buf.Bytes()
+	default:
+		return fmt.Errorf("unknown snapshot format %q", format)
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// Merge adds the entries of other to the snapshot. Flags of entries with
+// equal contexts are replaced by the flags of other.
+func (s *Snapshot) Merge(other Snapshot) {
+	for _, e := range other.Entries {
+		i := s.entry(e.Context)
+		if i < 0 {
+			s.Entries = append(s.Entries, SnapshotEntry{Context: e.Context, Flags: make(map[string]SnapshotFlag)})
+			i = len(s.Entries) - 1
+		}
+
+		if s.Entries[i].Flags == nil {
+			s.Entries[i].Flags = make(map[string]SnapshotFlag)
+		}
+
+		for flag, f := range e.Flags {
+			s.Entries[i].Flags[flag] = f
+		}
+	}
+}
+
+// entry returns the index of the entry for an evaluation context or -1.
+// Contexts that cannot be encoded match no entry.
+func (s *Snapshot) entry(evalCtx eval.Context) int {
+	key, err := encodeContext(evalCtx)
+	if err != nil {
+		return -1
+	}
+
+	for i, e := range s.Entries {
+		if k, err := encodeContext(e.Context); err == nil && k == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Import sets the flags of the snapshot entries without a context. Entries
+// with a context cannot be represented by InMemory and are ignored.
+func (i *InMemory) Import(s Snapshot) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.Flags == nil {
+		i.Flags = make(map[string]InMemoryFlag)
+	}
+
+	for _, e := range s.Entries {
+		if len(e.Context) != 0 {
+			continue
+		}
+
+		for flag, f := range e.Flags {
+			i.Flags[flag] = InMemoryFlag{Value: f.Value}
+		}
+	}
+}
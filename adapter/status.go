@@ -0,0 +1,170 @@
+package adapter
+
+import "sync"
+
+// Status is the readiness of an adapter to serve flags.
+type Status int
+
+const (
+	// StatusNotReady is the status of an adapter that has not loaded its
+	// initial data yet.
+	StatusNotReady Status = iota
+
+	// StatusReady is the status of an adapter that serves current data.
+	StatusReady
+
+	// StatusStale is the status of an adapter that serves data that it
+	// failed to refresh.
+	StatusStale
+
+	// StatusError is the status of an adapter that failed to load its
+	// initial data and cannot serve flags.
+	StatusError
+)
+
+// String returns the name of the status, e.g. not_ready.
+func (s Status) String() string {
+	switch s {
+	case StatusNotReady:
+		return "not_ready"
+	case StatusReady:
+		return "ready"
+	case StatusStale:
+		return "stale"
+	case StatusError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Serving reports whether an adapter with the status serves flags, i.e. it
+// is ready or stale.
+func (s Status) Serving() bool {
+	return s == StatusReady || s == StatusStale
+}
+
+// StatusEvent describes a change of the status of an adapter.
+type StatusEvent struct {
+	Status Status
+
+	// Err is the error that caused a stale or error status.
+	Err error
+}
+
+// StatusReporter is implemented by adapters that are not ready as soon as
+// they are created, e.g. because they load flags in the background.
+// Adapters that do not implement it are always ready.
+type StatusReporter interface {
+	// Status returns the current status and, if the adapter is stale or
+	// failed, the error that caused it.
+	Status() (Status, error)
+
+	// WatchStatus calls fn with every change of the status until stop is
+	// called. fn must not block.
+	WatchStatus(fn func(StatusEvent)) (stop func())
+}
+
+// Wrapper is implemented by adapters that wrap another adapter, e.g.
+// middlewares. StatusOf and WatchStatus look through them.
+type Wrapper interface {
+	Unwrap() Adapter
+}
+
+// StatusOf returns the status of a, looking through wrappers. Adapters that
+// do not report a status are ready.
+func StatusOf(a Adapter) (Status, error) {
+	if r, ok := statusReporter(a); ok {
+		return r.Status()
+	}
+
+	return StatusReady, nil
+}
+
+// WatchStatus calls fn with every change of the status of a until stop is
+// called, looking through wrappers. It does nothing for adapters that do not
+// report a status.
+func WatchStatus(a Adapter, fn func(StatusEvent)) (stop func()) {
+	if r, ok := statusReporter(a); ok {
+		return r.WatchStatus(fn)
+	}
+
+	return func() {}
+}
+
+func statusReporter(a Adapter) (StatusReporter, bool) {
+	for a != nil {
+		if r, ok := a.(StatusReporter); ok {
+			return r, true
+		}
+
+		w, ok := a.(Wrapper)
+		if !ok {
+			break
+		}
+		a = w.Unwrap()
+	}
+
+	return nil, false
+}
+
+// StatusTracker keeps the status of an adapter and notifies its watchers.
+// Adapters implement StatusReporter by delegating to it. The zero value is
+// not ready.
+type StatusTracker struct {
+	mu       sync.Mutex
+	status   Status
+	err      error
+	watchers map[int]func(StatusEvent)
+	next     int
+}
+
+// Status returns the current status and the error that caused it.
+func (t *StatusTracker) Status() (Status, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.status, t.err
+}
+
+// SetStatus sets the status and notifies the watchers if it changed or err
+// is not nil.
+func (t *StatusTracker) SetStatus(s Status, err error) {
+	t.mu.Lock()
+	if t.status == s && t.err == nil && err == nil {
+		t.mu.Unlock()
+		return
+	}
+
+	t.status, t.err = s, err
+	watchers := make([]func(StatusEvent), 0, len(t.watchers))
+	for _, fn := range t.watchers {
+		watchers = append(watchers, fn)
+	}
+	t.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(StatusEvent{Status: s, Err: err})
+	}
+}
+
+// WatchStatus calls fn with every change of the status until stop is called.
+func (t *StatusTracker) WatchStatus(fn func(StatusEvent)) (stop func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.watchers == nil {
+		t.watchers = make(map[int]func(StatusEvent))
+	}
+
+	id := t.next
+	t.next++
+	t.watchers[id] = fn
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		delete(t.watchers, id)
+	}
+}
@@ -0,0 +1,146 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadingAdapter is an adapter whose status is set by the test. It mirrors
+// testutil.LoadingAdapter, which cannot be used here because it imports this
+// package.
+type loadingAdapter struct {
+	*InMemory
+	StatusTracker
+}
+
+func TestStatusOf(t *testing.T) {
+	if s, err := StatusOf(NewInMemory()); s != StatusReady || err != nil {
+		t.Fatalf("expected adapters without status to be ready, got %s, %v", s, err)
+	}
+
+	loading := &loadingAdapter{InMemory: NewInMemory()}
+	a := Chain(loading, WithCache(time.Minute), WithTimeout(time.Second))
+
+	if s, _ := StatusOf(a); s != StatusNotReady {
+		t.Fatalf("expected status not_ready through middlewares, got %s", s)
+	}
+
+	var events []StatusEvent
+	stop := WatchStatus(a, func(e StatusEvent) {
+		events = append(events, e)
+	})
+
+	loading.SetStatus(StatusReady, nil)
+	loading.SetStatus(StatusReady, nil)
+	stop()
+	loading.SetStatus(StatusError, errUnavailable)
+
+	if len(events) != 1 || events[0].Status != StatusReady {
+		t.Fatalf("expected 1 ready event, got %+v", events)
+	}
+
+	if s, err := StatusOf(a); s != StatusError || !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected status error with unavailable error, got %s, %v", s, err)
+	}
+}
+
+func TestFileStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.yaml")
+	if err := os.WriteFile(path, []byte(testSnapshot), 0o644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	f := NewFile(path)
+	if s, _ := f.Status(); s != StatusNotReady {
+		t.Fatalf("expected status not ready before init, got %s", s)
+	}
+
+	if err := Init(context.TODO(), f); err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+
+	if s, _ := f.Status(); s != StatusReady {
+		t.Fatalf("expected status ready, got %s", s)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove snapshot: %v", err)
+	}
+
+	if err := f.Reload(); err == nil {
+		t.Fatalf("expected reload to fail")
+	}
+
+	if s, err := f.Status(); s != StatusStale || err == nil {
+		t.Fatalf("expected status stale with an error, got %s, %v", s, err)
+	}
+}
+
+func TestFallbackStatus(t *testing.T) {
+	primary := &loadingAdapter{InMemory: NewInMemory()}
+	secondary := &loadingAdapter{InMemory: NewInMemory()}
+	f := NewFallback(FallbackLink{Adapter: primary}, FallbackLink{Adapter: secondary})
+
+	var events []StatusEvent
+	stop := f.WatchStatus(func(e StatusEvent) {
+		events = append(events, e)
+	})
+	defer stop()
+
+	for _, tc := range []struct {
+		set      *loadingAdapter
+		status   Status
+		expected Status
+	}{
+		{primary, StatusError, StatusError},
+		{secondary, StatusStale, StatusStale},
+		{primary, StatusReady, StatusReady},
+	} {
+		tc.set.SetStatus(tc.status, nil)
+		if s, _ := f.Status(); s != tc.expected {
+			t.Fatalf("expected status %s, got %s", tc.expected, s)
+		}
+	}
+
+	if len(events) != 3 || events[2].Status != StatusReady {
+		t.Fatalf("expected 3 events ending with ready, got %+v", events)
+	}
+}
+
+func TestFallbackStatusIgnored(t *testing.T) {
+	primary := &loadingAdapter{InMemory: NewInMemory()}
+	f := NewFallback(
+		FallbackLink{Adapter: primary},
+		FallbackLink{Adapter: NewInMemory(), IgnoreStatus: true},
+	)
+
+	if s, _ := f.Status(); s != StatusNotReady {
+		t.Fatalf("expected status not ready, got %s", s)
+	}
+
+	primary.SetStatus(StatusReady, nil)
+	if s, _ := f.Status(); s != StatusReady {
+		t.Fatalf("expected status ready, got %s", s)
+	}
+
+	f = NewFallback(FallbackLink{Adapter: &loadingAdapter{InMemory: NewInMemory()}, IgnoreStatus: true})
+	if s, _ := f.Status(); s != StatusReady {
+		t.Fatalf("expected chain without counted adapters to be ready, got %s", s)
+	}
+}
+
+func TestFileStatusMissing(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	if err := Init(context.TODO(), f); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected init to fail with ErrNotExist, got %v", err)
+	}
+
+	if s, err := f.Status(); s != StatusError || err == nil {
+		t.Fatalf("expected status error with an error, got %s, %v", s, err)
+	}
+}
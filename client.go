@@ -5,14 +5,56 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/kickplan/sdk-go/adapter"
 	"github.com/kickplan/sdk-go/eval"
 )
 
+var (
+	globalMu      sync.RWMutex
+	globalEvalCtx eval.Context
+)
+
+// SetEvalContext sets the global evaluation context shared by all clients.
+//
+// Evaluation contexts are merged before they reach the adapter, from the
+// lowest to the highest precedence:
+//
+//  1. the global context set with SetEvalContext,
+//  2. the client context set with WithEvalContext,
+//  3. the transaction context stored in ctx with eval.NewContext,
+//  4. the invocation context passed to the method.
+func SetEvalContext(evalCtx eval.Context) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalEvalCtx = eval.Merge(evalCtx)
+}
+
+// EvalContext returns the global evaluation context.
+func EvalContext() eval.Context {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+
+	return globalEvalCtx
+}
+
 // Client is a Kickplan client.
 type Client struct {
+	mu      sync.RWMutex
 	adapter adapter.Adapter
+	evalCtx eval.Context
+	hooks   []Hook
+
+	statusHandlers []func(adapter.StatusEvent)
+	stopWatch      func()
+
+	closed   bool
+	inflight sync.WaitGroup
+
+	entitlements map[string]Entitlement
+	consumeLocks [64]sync.Mutex
 }
 
 // Option is a function that configures a Client.
@@ -28,7 +70,7 @@ func NewClient(opt ...Option) *Client {
 		}
 	}
 
-	if os.Getenv("KICKPLAN_ACCESS_TOKEN") != "" {
+	if c.adapter == nil && os.Getenv("KICKPLAN_ACCESS_TOKEN") != "" {
 		c.adapter = adapter.NewKickplan(
 			os.Getenv("KICKPLAN_ENDPOINT"),
 			os.Getenv("KICKPLAN_ACCESS_TOKEN"),
@@ -41,10 +83,13 @@ func NewClient(opt ...Option) *Client {
 		c.adapter = adapter.NewInMemory()
 	}
 
+	c.watchStatus()
+
 	return c
 }
 
-// WithAdapter sets the provider for the client.
+// WithAdapter sets the provider for the client. It takes precedence over the
+// adapter configured by the KICKPLAN_ACCESS_TOKEN environment variable.
 func WithAdapter(a adapter.Adapter) Option {
 	return func(c *Client) error {
 		c.adapter = a
@@ -52,14 +97,47 @@ func WithAdapter(a adapter.Adapter) Option {
 	}
 }
 
+// Adapter returns the adapter used by the client.
+func (c *Client) Adapter() adapter.Adapter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.adapter
+}
+
+// WrapAdapter replaces the adapter used by the client with the adapter
+// returned by wrap, which receives the current one. It is safe to call while
+// the client is in use and does nothing after Close.
+func (c *Client) WrapAdapter(wrap func(adapter.Adapter) adapter.Adapter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.adapter = wrap(c.adapter)
+	c.watchStatus()
+}
+
+// WithEvalContext sets the evaluation context used by every call of the client.
+// See SetEvalContext for how it is merged with other contexts.
+func WithEvalContext(evalCtx eval.Context) Option {
+	return func(c *Client) error {
+		c.evalCtx = eval.Merge(evalCtx)
+		return nil
+	}
+}
+
 // GetBool returns a boolean flag.
 func (c *Client) GetBool(
 	ctx context.Context,
 	flag string,
 	defaultValue bool,
 	evalCtx eval.Context,
+	opt ...EvalOption,
 ) (bool, error) {
-	return c.adapter.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+	return evaluate(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.BooleanEvaluation, opt)
 }
 
 // GetInt64 returns a float64 flag.
@@ -68,8 +146,9 @@ func (c *Client) GetInt64(
 	flag string,
 	defaultValue int64,
 	evalCtx eval.Context,
+	opt ...EvalOption,
 ) (int64, error) {
-	return c.adapter.Int64Evaluation(ctx, flag, defaultValue, evalCtx)
+	return evaluate(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.Int64Evaluation, opt)
 }
 
 // GetString returns a string flag.
@@ -78,8 +157,9 @@ func (c *Client) GetString(
 	flag string,
 	defaultValue string,
 	evalCtx eval.Context,
+	opt ...EvalOption,
 ) (string, error) {
-	return c.adapter.StringEvaluation(ctx, flag, defaultValue, evalCtx)
+	return evaluate(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.StringEvaluation, opt)
 }
 
 // GetObject returns a object flag.
@@ -88,23 +168,124 @@ func (c *Client) GetObject(
 	flag string,
 	defaultValue interface{},
 	evalCtx eval.Context,
+	opt ...EvalOption,
 ) (interface{}, error) {
-	return c.adapter.ObjectEvaluation(ctx, flag, defaultValue, evalCtx)
+	return evaluate(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.ObjectEvaluation, opt)
+}
+
+// GetBoolDetails returns a boolean flag with the details of its evaluation.
+func (c *Client) GetBoolDetails(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+	opt ...EvalOption,
+) (EvaluationDetails, error) {
+	_, details, err := evaluateDetails(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.BooleanEvaluation, opt)
+	return details, err
+}
+
+// GetInt64Details returns an int64 flag with the details of its evaluation.
+func (c *Client) GetInt64Details(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+	opt ...EvalOption,
+) (EvaluationDetails, error) {
+	_, details, err := evaluateDetails(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.Int64Evaluation, opt)
+	return details, err
+}
+
+// GetStringDetails returns a string flag with the details of its evaluation.
+func (c *Client) GetStringDetails(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+	opt ...EvalOption,
+) (EvaluationDetails, error) {
+	_, details, err := evaluateDetails(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.StringEvaluation, opt)
+	return details, err
+}
+
+// GetObjectDetails returns an object flag with the details of its evaluation.
+func (c *Client) GetObjectDetails(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+	opt ...EvalOption,
+) (EvaluationDetails, error) {
+	_, details, err := evaluateDetails(ctx, c, flag, defaultValue, evalCtx, adapter.Adapter.ObjectEvaluation, opt)
+	return details, err
+}
+
+// GetAll evaluates all flags at once, e.g. to inspect what an account is
+// entitled to. Hooks, including the ones passed with WithCallHook, are not
+// notified about bulk evaluations. It returns
+// adapter.ErrBulkEvaluationUnsupported if the adapter cannot evaluate all
+// flags at once.
+func (c *Client) GetAll(ctx context.Context, evalCtx eval.Context, opt ...EvalOption) ([]EvaluationDetails, error) {
+	o := newEvalOptions(opt)
+	evalCtx = c.mergeEvalContext(ctx, evalCtx)
+
+	resolveCtx, cancel := o.context(ctx)
+	defer cancel()
+
+	var evaluations []adapter.FlagEvaluation
+	err := c.call(func(a adapter.Adapter) error {
+		var err error
+		evaluations, err = adapter.AllEvaluations(resolveCtx, a, evalCtx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]EvaluationDetails, 0, len(evaluations))
+	for _, e := range evaluations {
+		all = append(all, EvaluationDetails{
+			Flag:        e.Flag,
+			Value:       e.Value,
+			Variant:     e.Variant,
+			Reason:      e.Reason,
+			ErrorCode:   e.ErrorCode,
+			Adapter:     e.Adapter,
+			EvalContext: evalCtx,
+			Tags:        o.call.Tags,
+		})
+	}
+
+	return all, nil
 }
 
 // SetBool sets a boolean flag.
 func (c *Client) SetBool(ctx context.Context, flag string, value bool) error {
-	return c.adapter.SetBoolean(ctx, flag, value)
+	return c.call(func(a adapter.Adapter) error {
+		return a.SetBoolean(ctx, flag, value)
+	})
 }
 
 // SetMetric sets a metric.
+//
+// Metric updates carry an idempotency key, so that an update is applied once
+// even when it is sent again. A random key is generated for every call
+// unless one is passed with adapter.WithIdempotencyKey; pass the same key
+// when retrying an update, e.g. one derived from a queue message ID.
+//
+// Pass adapter.WithOccurredAt to report usage that happened earlier, and
+// adapter.WithUnit and adapter.WithAttributes to describe the update.
 func (c *Client) SetMetric(
 	ctx context.Context,
 	metric string,
 	value int64,
 	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
 ) error {
-	return c.adapter.SetMetric(ctx, metric, value, evalCtx)
+	return c.call(func(a adapter.Adapter) error {
+		return a.SetMetric(metricContext(ctx, opt), metric, value, c.mergeEvalContext(ctx, evalCtx))
+	})
 }
 
 // IncMetric increments a metric.
@@ -113,8 +294,11 @@ func (c *Client) IncMetric(
 	metric string,
 	value int64,
 	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
 ) error {
-	return c.adapter.IncMetric(ctx, metric, value, evalCtx)
+	return c.call(func(a adapter.Adapter) error {
+		return a.IncMetric(metricContext(ctx, opt), metric, value, c.mergeEvalContext(ctx, evalCtx))
+	})
 }
 
 // DecMetric decrements a metric.
@@ -123,6 +307,95 @@ func (c *Client) DecMetric(
 	metric string,
 	value int64,
 	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
+) error {
+	return c.call(func(a adapter.Adapter) error {
+		return a.DecMetric(metricContext(ctx, opt), metric, value, c.mergeEvalContext(ctx, evalCtx))
+	})
+}
+
+// AddCounter adds value to a monotonic counter, e.g. the number of API
+// calls of an account. Counters cannot be decremented, so negative values are
+// rejected with adapter.ErrNegativeCounter.
+func (c *Client) AddCounter(
+	ctx context.Context,
+	metric string,
+	value float64,
+	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
+) error {
+	return c.recordMetric(ctx, metric, adapter.MetricKindCounter, value, evalCtx, opt)
+}
+
+// SetGauge sets a gauge, a metric that can go up and down, e.g. the storage
+// used by an account.
+func (c *Client) SetGauge(
+	ctx context.Context,
+	metric string,
+	value float64,
+	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
+) error {
+	return c.recordMetric(ctx, metric, adapter.MetricKindGauge, value, evalCtx, opt)
+}
+
+// Observe records an observation of a histogram, e.g. the size or latency of
+// a request.
+func (c *Client) Observe(
+	ctx context.Context,
+	metric string,
+	value float64,
+	evalCtx eval.Context,
+	opt ...adapter.MetricOption,
+) error {
+	return c.recordMetric(ctx, metric, adapter.MetricKindHistogram, value, evalCtx, opt)
+}
+
+func (c *Client) recordMetric(
+	ctx context.Context,
+	metric string,
+	kind adapter.MetricKind,
+	value float64,
+	evalCtx eval.Context,
+	opt []adapter.MetricOption,
 ) error {
-	return c.adapter.DecMetric(ctx, metric, value, evalCtx)
+	return c.call(func(a adapter.Adapter) error {
+		return adapter.RecordMetric(metricContext(ctx, opt), a, metric, kind, value, c.mergeEvalContext(ctx, evalCtx))
+	})
+}
+
+// GetMetric returns the current value of a metric and the time it was last
+// updated. It returns adapter.ErrMetricReadUnsupported if the adapter cannot
+// read metrics.
+func (c *Client) GetMetric(
+	ctx context.Context,
+	metric string,
+	evalCtx eval.Context,
+) (adapter.Metric, error) {
+	var m adapter.Metric
+	err := c.call(func(a adapter.Adapter) error {
+		var err error
+		m, err = adapter.ReadMetric(ctx, a, metric, c.mergeEvalContext(ctx, evalCtx))
+		return err
+	})
+
+	return m, err
+}
+
+// metricContext returns a copy of ctx that carries the options of a metric
+// update to the adapter. A generated idempotency key is added unless the
+// caller supplied one.
+func metricContext(ctx context.Context, opt []adapter.MetricOption) context.Context {
+	o := adapter.NewMetricOptions(opt...)
+	if o.IdempotencyKey == "" {
+		o.IdempotencyKey = adapter.NewIdempotencyKey()
+	}
+
+	return adapter.WithMetricOptions(ctx, o)
+}
+
+// mergeEvalContext merges the global, client, transaction and invocation
+// evaluation contexts.
+func (c *Client) mergeEvalContext(ctx context.Context, evalCtx eval.Context) eval.Context {
+	return eval.Merge(EvalContext(), c.evalCtx, eval.FromContext(ctx), evalCtx)
 }
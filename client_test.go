@@ -2,9 +2,13 @@ package kickplan
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+	"github.com/kickplan/sdk-go/internal/testutil"
 )
 
 func TestDefaultAdapter(t *testing.T) {
@@ -44,3 +48,292 @@ func TestDefaultAdapter(t *testing.T) {
 		t.Fatalf("expected flag to be true")
 	}
 }
+
+func TestWithAdapterOverridesEnv(t *testing.T) {
+	t.Setenv("KICKPLAN_ACCESS_TOKEN", "token")
+
+	client := NewClient(WithAdapter(adapter.NewInMemory()))
+	if _, ok := client.adapter.(*adapter.InMemory); !ok {
+		t.Fatalf("expected adapter to be of type InMemory")
+	}
+}
+
+func TestLayeredEvalContext(t *testing.T) {
+	SetEvalContext(eval.Context{"env": "prod", "region": "eu", "service": "api"})
+	t.Cleanup(func() { SetEvalContext(nil) })
+
+	a := testutil.NewContextAdapter()
+	client := NewClient(
+		WithAdapter(a),
+		WithEvalContext(eval.Context{"region": "us", "account_id": "client"}),
+	)
+
+	ctx := eval.NewContext(context.TODO(), eval.Context{"account_id": "transaction", "user_id": "user"})
+	if _, err := client.GetBool(ctx, "my-flag", false, eval.Context{"user_id": "invocation"}); err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	expected := eval.Context{
+		"env":        "prod",
+		"region":     "us",
+		"service":    "api",
+		"account_id": "transaction",
+		"user_id":    "invocation",
+	}
+	evalCtx := a.EvalContext()
+	if len(evalCtx) != len(expected) {
+		t.Fatalf("expected evaluation context to be %v, got %v", expected, evalCtx)
+	}
+
+	for k, v := range expected {
+		if evalCtx[k] != v {
+			t.Fatalf("expected evaluation context %s to be %v, got %v", k, v, evalCtx[k])
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	var details []EvaluationDetails
+	client := NewClient(
+		WithAdapter(adapter.NewInMemory()),
+		WithHook(HookFunc(func(_ context.Context, d EvaluationDetails, _ error) {
+			details = append(details, d)
+		})),
+	)
+
+	if _, err := client.GetBool(context.TODO(), "my-flag", false, nil); err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	if err := client.SetBool(context.TODO(), "my-flag", true); err != nil {
+		t.Fatalf("failed to set flag: %v", err)
+	}
+
+	if _, err := client.GetString(context.TODO(), "my-flag", "", nil); err == nil {
+		t.Fatalf("expected type mismatch error")
+	}
+
+	expected := []EvaluationDetails{
+		{Flag: "my-flag", Value: false, Reason: adapter.ReasonDefault},
+		{Flag: "my-flag", Value: "", Reason: adapter.ReasonError, ErrorCode: "TYPE_MISMATCH"},
+	}
+	if len(details) != len(expected) {
+		t.Fatalf("expected %d evaluations, got %d", len(expected), len(details))
+	}
+
+	for i := range expected {
+		d := details[i]
+		if d.Flag != expected[i].Flag || d.Value != expected[i].Value ||
+			d.Reason != expected[i].Reason || d.ErrorCode != expected[i].ErrorCode {
+			t.Fatalf("expected evaluation %d to be %+v, got %+v", i, expected[i], d)
+		}
+	}
+}
+
+func TestGetMetric(t *testing.T) {
+	client := NewClient(
+		WithAdapter(adapter.NewInMemory()),
+		WithEvalContext(eval.Context{"env": "prod"}),
+	)
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if err := client.IncMetric(context.TODO(), "seats", 3, evalCtx); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	m, err := client.GetMetric(context.TODO(), "seats", evalCtx)
+	if err != nil {
+		t.Fatalf("failed to get metric: %v", err)
+	}
+
+	if m.Value != 3 {
+		t.Fatalf("expected metric value to be 3, got %d", m.Value)
+	}
+
+	if m.UpdatedAt.IsZero() {
+		t.Fatalf("expected metric updated at to be set")
+	}
+}
+
+func TestMetricsUnsupported(t *testing.T) {
+	// an adapter that only implements the Adapter interface
+	client := NewClient(WithAdapter(struct{ adapter.Adapter }{adapter.NewInMemory()}))
+
+	if err := client.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if _, err := client.GetMetric(context.TODO(), "seats", nil); !errors.Is(err, adapter.ErrMetricReadUnsupported) {
+		t.Fatalf("expected error %v, got %v", adapter.ErrMetricReadUnsupported, err)
+	}
+
+	if err := client.AddCounter(context.TODO(), "api_calls", 1, nil); !errors.Is(err, adapter.ErrMetricRecordUnsupported) {
+		t.Fatalf("expected error %v, got %v", adapter.ErrMetricRecordUnsupported, err)
+	}
+}
+
+func TestMetricIdempotencyKey(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	client := NewClient(WithAdapter(inMemory))
+
+	for range 2 {
+		if err := client.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	for range 2 {
+		if err := client.IncMetric(context.TODO(), "seats", 1, nil, adapter.WithIdempotencyKey("retry")); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	updates := inMemory.MetricUpdates()
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 metric updates, got %d", len(updates))
+	}
+
+	if updates[0].IdempotencyKey == "" || updates[0].IdempotencyKey == updates[1].IdempotencyKey {
+		t.Fatalf("expected generated idempotency keys to be unique, got %q and %q",
+			updates[0].IdempotencyKey, updates[1].IdempotencyKey)
+	}
+
+	if updates[2].IdempotencyKey != "retry" {
+		t.Fatalf("expected idempotency key to be retry, got %q", updates[2].IdempotencyKey)
+	}
+}
+
+func TestMetricKinds(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	client := NewClient(WithAdapter(inMemory))
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if err := client.AddCounter(context.TODO(), "api_calls", 1, evalCtx); err != nil {
+		t.Fatalf("failed to add counter: %v", err)
+	}
+
+	if err := client.AddCounter(context.TODO(), "api_calls", -1, evalCtx); !errors.Is(err, adapter.ErrNegativeCounter) {
+		t.Fatalf("expected error %v, got %v", adapter.ErrNegativeCounter, err)
+	}
+
+	if err := client.SetGauge(context.TODO(), "storage", 512.5, evalCtx); err != nil {
+		t.Fatalf("failed to set gauge: %v", err)
+	}
+
+	if err := client.Observe(context.TODO(), "request_size", 1024, evalCtx, adapter.WithUnit("bytes")); err != nil {
+		t.Fatalf("failed to observe histogram: %v", err)
+	}
+
+	expected := map[string]adapter.MetricKind{
+		"api_calls":    adapter.MetricKindCounter,
+		"storage":      adapter.MetricKindGauge,
+		"request_size": adapter.MetricKindHistogram,
+	}
+
+	for metric, kind := range expected {
+		s, ok := inMemory.GetSeries(metric, evalCtx)
+		if !ok {
+			t.Fatalf("expected metric %s to be recorded", metric)
+		}
+
+		if s.Kind != kind {
+			t.Fatalf("expected metric %s to be a %s, got %s", metric, kind, s.Kind)
+		}
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["enabled"] = adapter.InMemoryFlag{Value: true}
+	inMemory.Flags["plan"] = adapter.InMemoryFlag{Value: "pro"}
+	client := NewClient(WithAdapter(inMemory))
+
+	d, err := client.GetBoolDetails(context.TODO(), "enabled", false, eval.Context{"account_id": "account"})
+	if err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	if d.Flag != "enabled" || d.Value != true || d.Reason != adapter.ReasonStatic {
+		t.Fatalf("unexpected details %+v", d)
+	}
+
+	if d.EvalContext["account_id"] != "account" {
+		t.Fatalf("expected context account_id to be account, got %v", d.EvalContext["account_id"])
+	}
+
+	d, err = client.GetInt64Details(context.TODO(), "plan", 0, nil)
+	if !errors.Is(err, adapter.ErrTypeMismatch) {
+		t.Fatalf("expected error %v, got %v", adapter.ErrTypeMismatch, err)
+	}
+
+	if d.Reason != adapter.ReasonError || d.ErrorCode != "TYPE_MISMATCH" {
+		t.Fatalf("expected reason ERROR with code TYPE_MISMATCH, got %+v", d)
+	}
+
+	all, err := client.GetAll(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("failed to get all flags: %v", err)
+	}
+
+	if len(all) != 2 || all[0].Flag != "enabled" || all[1].Flag != "plan" || all[1].Value != "pro" {
+		t.Fatalf("unexpected evaluations %+v", all)
+	}
+}
+
+func TestEvalOptions(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["enabled"] = adapter.InMemoryFlag{Value: true}
+	client := NewClient(WithAdapter(adapter.Chain(inMemory, adapter.WithCache(time.Minute))))
+
+	var details []EvaluationDetails
+	hook := WithCallHook(HookFunc(func(_ context.Context, d EvaluationDetails, _ error) {
+		details = append(details, d)
+	}))
+
+	_, _ = client.GetBool(context.TODO(), "enabled", false, nil)
+	inMemory.Flags["enabled"] = adapter.InMemoryFlag{Value: false}
+
+	for _, tc := range []struct {
+		opt    EvalOption
+		value  bool
+		reason string
+	}{
+		{hook, true, adapter.ReasonCached},
+		{BypassCache(), false, adapter.ReasonStatic},
+		{hook, true, adapter.ReasonCached},
+		{RequireFresh(), false, adapter.ReasonStatic},
+		{hook, false, adapter.ReasonCached},
+	} {
+		d, err := client.GetBoolDetails(context.TODO(), "enabled", true, nil, tc.opt)
+		if err != nil || d.Value != tc.value || d.Reason != tc.reason {
+			t.Fatalf("expected %v with reason %s, got %+v, %v", tc.value, tc.reason, d, err)
+		}
+	}
+
+	if len(details) != 3 {
+		t.Fatalf("expected the call hook to be notified 3 times, got %d", len(details))
+	}
+
+	_, _ = client.GetBool(context.TODO(), "enabled", false, nil, hook,
+		WithTags(map[string]string{"route": "/billing"}),
+		WithTags(map[string]string{"team": "payments"}),
+	)
+
+	if tags := details[3].Tags; tags["route"] != "/billing" || tags["team"] != "payments" {
+		t.Fatalf("expected tags route and team, got %v", tags)
+	}
+}
+
+func TestEvalOptionsTimeout(t *testing.T) {
+	client := NewClient(WithAdapter(adapter.Chain(adapter.NewInMemory(), adapter.Intercept(
+		func(ctx context.Context, _ *adapter.Call, next func(context.Context) error) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	))))
+
+	v, err := client.GetBool(context.TODO(), "enabled", true, nil, WithCallTimeout(time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) || !v {
+		t.Fatalf("expected default value and deadline exceeded, got %v, %v", v, err)
+	}
+}
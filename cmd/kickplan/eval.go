@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// details is the JSON representation of kickplan.EvaluationDetails.
+type details struct {
+	Flag      string       `json:"flag"`
+	Value     interface{}  `json:"value"`
+	Variant   string       `json:"variant,omitempty"`
+	Reason    string       `json:"reason,omitempty"`
+	ErrorCode string       `json:"error_code,omitempty"`
+	Adapter   string       `json:"adapter,omitempty"`
+	Context   eval.Context `json:"context,omitempty"`
+}
+
+func newDetails(d kickplan.EvaluationDetails) details {
+	return details{
+		Flag:      d.Flag,
+		Value:     d.Value,
+		Variant:   d.Variant,
+		Reason:    d.Reason,
+		ErrorCode: d.ErrorCode,
+		Adapter:   d.Adapter,
+		Context:   d.EvalContext,
+	}
+}
+
+func runEval(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := newFlagSet("eval")
+	typ := fs.String("type", "object", "type of the flag: bool, int64, string or object")
+	defaultValue := fs.String("default", "", "default value of the flag")
+	showDetails := fs.Bool("details", false, "print the details of the evaluation")
+	snapshot := fs.String("snapshot", "", "evaluate against a snapshot file instead of the API")
+	evalCtx := contextVar(fs)
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: eval expects a flag", errUsage)
+	}
+
+	def, err := parseDefault(*typ, *defaultValue)
+	if err != nil {
+		return err
+	}
+
+	client, err := newSnapshotClient(ctx, *snapshot)
+	if err != nil {
+		return err
+	}
+
+	d, err := evaluate(ctx, client, positional[0], def, evalCtx)
+	if *showDetails {
+		if werr := writeJSON(stdout, newDetails(d)); werr != nil {
+			return werr
+		}
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(stdout, d.Value)
+}
+
+// parseDefault parses the default value of a flag of the given type.
+// Object defaults are parsed as JSON.
+func parseDefault(typ string, s string) (interface{}, error) {
+	switch typ {
+	case "bool":
+		if s == "" {
+			return false, nil
+		}
+
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid bool default %q", errUsage, s)
+		}
+
+		return b, nil
+	case "int64":
+		if s == "" {
+			return int64(0), nil
+		}
+
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid int64 default %q", errUsage, s)
+		}
+
+		return i, nil
+	case "string":
+		return s, nil
+	case "object":
+		if s == "" {
+			return nil, nil
+		}
+
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("%w: invalid JSON default %q", errUsage, s)
+		}
+
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", errUsage, typ)
+	}
+}
+
+// evaluate evaluates a flag with the type of its default value.
+func evaluate(
+	ctx context.Context,
+	client *kickplan.Client,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (kickplan.EvaluationDetails, error) {
+	switch v := defaultValue.(type) {
+	case bool:
+		return client.GetBoolDetails(ctx, flag, v, evalCtx)
+	case int64:
+		return client.GetInt64Details(ctx, flag, v, evalCtx)
+	case string:
+		return client.GetStringDetails(ctx, flag, v, evalCtx)
+	default:
+		return client.GetObjectDetails(ctx, flag, v, evalCtx)
+	}
+}
+
+func runAll(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := newFlagSet("all")
+	asJSON := fs.Bool("json", false, "print the evaluations as JSON")
+	snapshot := fs.String("snapshot", "", "evaluate against a snapshot file instead of the API")
+	evalCtx := contextVar(fs)
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 {
+		return fmt.Errorf("%w: all expects no arguments", errUsage)
+	}
+
+	client, err := newSnapshotClient(ctx, *snapshot)
+	if err != nil {
+		return err
+	}
+
+	all, err := client.GetAll(ctx, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		out := make([]details, 0, len(all))
+		for _, d := range all {
+			d.EvalContext = nil
+			out = append(out, newDetails(d))
+		}
+
+		return writeJSON(stdout, out)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FLAG\tVALUE\tVARIANT\tREASON\tERROR")
+	for _, d := range all {
+		value, _ := json.Marshal(d.Value)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Flag, value, d.Variant, d.Reason, d.ErrorCode)
+	}
+
+	return w.Flush()
+}
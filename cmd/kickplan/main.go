@@ -0,0 +1,184 @@
+// Command kickplan evaluates flags and updates metrics from the command line.
+//
+// Usage:
+//
+//	kickplan eval <flag> [--type bool|int64|string|object] [--default value] [--details] [--snapshot file] [--ctx key=value]...
+//	kickplan all [--json] [--snapshot file] [--ctx key=value]...
+//	kickplan metric <set|inc|dec|counter|gauge|observe> <metric> <value> [--ctx key=value]...
+//	kickplan metric get <metric> [--ctx key=value]...
+//	kickplan export [--out file] [--format json|yaml] [--contexts file] [--ctx key=value]...
+//	kickplan import <snapshot> <file>
+//
+// The client is configured with the KICKPLAN_ENDPOINT, KICKPLAN_ACCESS_TOKEN,
+// KICKPLAN_USER_AGENT and KICKPLAN_TIMEOUT environment variables, like
+// kickplan.NewClient does. With --snapshot, flags are evaluated against a
+// snapshot file written by export or import instead.
+//
+// Context attributes passed with --ctx key=value are strings. Use
+// --ctx key:=json to pass a JSON value, e.g. --ctx seats:=10.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+const usage = `Usage:
+  kickplan eval <flag> [--type bool|int64|string|object] [--default value] [--details] [--snapshot file] [--ctx key=value]...
+  kickplan all [--json] [--snapshot file] [--ctx key=value]...
+  kickplan metric <set|inc|dec|counter|gauge|observe> <metric> <value> [--ctx key=value]...
+  kickplan metric get <metric> [--ctx key=value]...
+  kickplan export [--out file] [--format json|yaml] [--contexts file] [--ctx key=value]...
+  kickplan import <snapshot> <file>
+
+Environment:
+  KICKPLAN_ENDPOINT, KICKPLAN_ACCESS_TOKEN, KICKPLAN_USER_AGENT, KICKPLAN_TIMEOUT
+`
+
+// errUsage is returned when a command is called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+type command func(ctx context.Context, args []string, stdout io.Writer) error
+
+var commands = map[string]command{
+	"eval":   runEval,
+	"all":    runAll,
+	"metric": runMetric,
+	"export": runExport,
+	"import": runImport,
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command given by args and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		_, _ = fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "kickplan: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err := cmd(ctx, args[1:], stdout); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			_, _ = fmt.Fprintf(stderr, "kickplan: %v\n\n%s", err, usage)
+			return 2
+		}
+
+		_, _ = fmt.Fprintf(stderr, "kickplan: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// newClient returns a client configured with environment variables.
+func newClient() (*kickplan.Client, error) {
+	if os.Getenv("KICKPLAN_ACCESS_TOKEN") == "" {
+		return nil, fmt.Errorf("KICKPLAN_ACCESS_TOKEN is not set")
+	}
+
+	return kickplan.NewClient(), nil
+}
+
+// newSnapshotClient returns a client that serves flags from a snapshot file
+// or, without a file, a client configured with environment variables.
+func newSnapshotClient(ctx context.Context, snapshot string) (*kickplan.Client, error) {
+	if snapshot == "" {
+		return newClient()
+	}
+
+	client := kickplan.NewClient(kickplan.WithAdapter(adapter.NewFile(snapshot)))
+	if err := client.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// newFlagSet returns a flag set that reports errors instead of printing them.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	return fs
+}
+
+// parse parses the flags of fs, which may be interleaved with positional
+// arguments, and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// contextFlag collects the attributes of an evaluation context passed with
+// --ctx key=value or --ctx key:=json.
+type contextFlag eval.Context
+
+func (f contextFlag) String() string {
+	b, _ := json.Marshal(eval.Context(f))
+	return string(b)
+}
+
+func (f contextFlag) Set(s string) error {
+	if key, raw, ok := strings.Cut(s, ":="); ok && key != "" {
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return fmt.Errorf("invalid JSON value of %q: %w", key, err)
+		}
+
+		f[key] = v
+		return nil
+	}
+
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value or key:=json, got %q", s)
+	}
+
+	f[key] = value
+	return nil
+}
+
+// contextVar defines a --ctx flag on fs and returns the context it collects.
+func contextVar(fs *flag.FlagSet) eval.Context {
+	evalCtx := make(eval.Context)
+	fs.Var(contextFlag(evalCtx), "ctx", "evaluation context attribute as key=value or key:=json (repeatable)")
+
+	return evalCtx
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
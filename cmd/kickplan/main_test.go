@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/kickplantest"
+)
+
+func newServer(t *testing.T) *kickplantest.Server {
+	server := kickplantest.NewServer(t)
+	t.Setenv("KICKPLAN_ENDPOINT", server.URL)
+	t.Setenv("KICKPLAN_ACCESS_TOKEN", kickplantest.Token)
+
+	return server
+}
+
+func runCommand(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.TODO(), args, &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), code
+}
+
+func TestEval(t *testing.T) {
+	server := newServer(t)
+	server.SetFlagDetails("my-flag", kickplantest.Flag{Value: true, Variant: "on", Reason: "TARGETING_MATCH"})
+
+	stdout, stderr, code := runCommand(t, "eval", "my-flag", "--ctx", "account_id=123", "--type", "bool")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if strings.TrimSpace(stdout) != "true" {
+		t.Fatalf("expected output true, got %q", stdout)
+	}
+
+	var req adapter.FeatureResolutionRequest
+	if err := json.Unmarshal(server.Requests()[0].Body, &req); err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+
+	if req.Context["account_id"] != "123" {
+		t.Fatalf("expected context account_id to be 123, got %v", req.Context["account_id"])
+	}
+}
+
+func TestEvalDetails(t *testing.T) {
+	server := newServer(t)
+	server.SetFlagDetails("seats", kickplantest.Flag{Value: 10, Variant: "pro", Reason: "TARGETING_MATCH"})
+
+	stdout, stderr, code := runCommand(t, "eval", "seats", "--type", "int64", "--details", "--ctx", "seats:=3")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	var d details
+	if err := json.Unmarshal([]byte(stdout), &d); err != nil {
+		t.Fatalf("failed to decode output %q: %v", stdout, err)
+	}
+
+	if d.Flag != "seats" || d.Value != float64(10) || d.Variant != "pro" || d.Reason != "TARGETING_MATCH" {
+		t.Fatalf("unexpected details %+v", d)
+	}
+
+	if d.Context["seats"] != float64(3) {
+		t.Fatalf("expected context seats to be 3, got %v", d.Context["seats"])
+	}
+}
+
+func TestEvalNotFound(t *testing.T) {
+	newServer(t)
+
+	_, stderr, code := runCommand(t, "eval", "missing", "--type", "bool")
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+
+	if !strings.Contains(stderr, "FLAG_NOT_FOUND") {
+		t.Fatalf("expected error FLAG_NOT_FOUND, got %q", stderr)
+	}
+}
+
+func TestAll(t *testing.T) {
+	server := newServer(t)
+	server.SetFlag("a", true)
+	server.SetFlag("b", "blue")
+
+	stdout, stderr, code := runCommand(t, "all", "--json")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	var all []details
+	if err := json.Unmarshal([]byte(stdout), &all); err != nil {
+		t.Fatalf("failed to decode output %q: %v", stdout, err)
+	}
+
+	if len(all) != 2 || all[0].Flag != "a" || all[0].Value != true || all[1].Flag != "b" || all[1].Value != "blue" {
+		t.Fatalf("unexpected evaluations %+v", all)
+	}
+}
+
+func TestMetric(t *testing.T) {
+	server := newServer(t)
+
+	if _, stderr, code := runCommand(t, "metric", "inc", "seats", "2", "--ctx", "account_id=123"); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if _, stderr, code := runCommand(t, "metric", "observe", "latency", "12.5", "--unit", "ms"); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	updates := server.MetricUpdates()
+	if len(updates) != 1 || updates[0].Operation != "increment" || updates[0].Value != 2 {
+		t.Fatalf("unexpected metric updates %+v", updates)
+	}
+
+	records := server.MetricRecords()
+	if len(records) != 1 || records[0].Kind != adapter.MetricKindHistogram || records[0].Unit != "ms" {
+		t.Fatalf("unexpected metric records %+v", records)
+	}
+
+	stdout, stderr, code := runCommand(t, "metric", "get", "seats", "--ctx", "account_id=123")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, `"value": 2`) {
+		t.Fatalf("expected metric value 2, got %q", stdout)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	newServer(t)
+
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"eval"},
+		{"eval", "my-flag", "--type", "float"},
+		{"metric", "inc", "seats", "two"},
+	} {
+		if _, _, code := runCommand(t, args...); code != 2 {
+			t.Fatalf("expected exit code 2 for %q, got %d", args, code)
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	server := newServer(t)
+	server.SetFlag("enabled", true)
+	server.SetFlag("seats", 10)
+
+	dir := t.TempDir()
+	exported := filepath.Join(dir, "export.json")
+	if _, stderr, code := runCommand(t, "export", "--out", exported, "--ctx", "account_id=123"); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	imported := filepath.Join(dir, "flags.yaml")
+	if _, stderr, code := runCommand(t, "import", exported, imported); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	stdout, stderr, code := runCommand(t, "eval", "seats", "--type", "int64", "--snapshot", imported,
+		"--ctx", "account_id=123")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if strings.TrimSpace(stdout) != "10" {
+		t.Fatalf("expected output 10, got %q", stdout)
+	}
+
+	// the snapshot was exported for account 123 only
+	if _, _, code := runCommand(t, "eval", "seats", "--snapshot", imported, "--ctx", "account_id=456"); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+}
+
+func TestWriteSnapshotFileKeepsTargetOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flags.json")
+
+	if err := os.WriteFile(path, []byte(`{"entries":[]}`), 0o644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	if err := writeSnapshotFile(path, adapter.Snapshot{}, "xml"); err == nil {
+		t.Fatalf("expected error for unknown format, got nil")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+
+	if string(b) != `{"entries":[]}` {
+		t.Fatalf("expected snapshot to be unchanged, got %q", b)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func runMetric(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := newFlagSet("metric")
+	unit := fs.String("unit", "", "unit of the value, e.g. bytes")
+	idempotencyKey := fs.String("idempotency-key", "", "idempotency key of the update (generated by default)")
+	occurredAt := fs.String("occurred-at", "", "time the usage occurred at in RFC 3339 format")
+	evalCtx := contextVar(fs)
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 2 && positional[0] == "get" {
+		return getMetric(ctx, positional[1], evalCtx, stdout)
+	}
+
+	if len(positional) != 3 {
+		return fmt.Errorf("%w: metric expects an operation, a metric and a value", errUsage)
+	}
+	operation, metric, value := positional[0], positional[1], positional[2]
+
+	var opt []adapter.MetricOption
+	if *unit != "" {
+		opt = append(opt, adapter.WithUnit(*unit))
+	}
+
+	if *idempotencyKey != "" {
+		opt = append(opt, adapter.WithIdempotencyKey(*idempotencyKey))
+	}
+
+	if *occurredAt != "" {
+		t, err := time.Parse(time.RFC3339, *occurredAt)
+		if err != nil {
+			return fmt.Errorf("%w: invalid occurred at %q", errUsage, *occurredAt)
+		}
+		opt = append(opt, adapter.WithOccurredAt(t))
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	switch operation {
+	case "set", "inc", "dec":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid int64 value %q", errUsage, value)
+		}
+
+		switch operation {
+		case "set":
+			return client.SetMetric(ctx, metric, v, evalCtx, opt...)
+		case "inc":
+			return client.IncMetric(ctx, metric, v, evalCtx, opt...)
+		default:
+			return client.DecMetric(ctx, metric, v, evalCtx, opt...)
+		}
+	case "counter", "gauge", "observe":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid float64 value %q", errUsage, value)
+		}
+
+		switch operation {
+		case "counter":
+			return client.AddCounter(ctx, metric, v, evalCtx, opt...)
+		case "gauge":
+			return client.SetGauge(ctx, metric, v, evalCtx, opt...)
+		default:
+			return client.Observe(ctx, metric, v, evalCtx, opt...)
+		}
+	default:
+		return fmt.Errorf("%w: unknown metric operation %q", errUsage, operation)
+	}
+}
+
+func getMetric(ctx context.Context, metric string, evalCtx eval.Context, stdout io.Writer) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	m, err := client.GetMetric(ctx, metric, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(stdout, struct {
+		Metric    string    `json:"metric"`
+		Value     int64     `json:"value"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}{metric, m.Value, m.UpdatedAt})
+}
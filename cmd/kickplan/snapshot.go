@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func runExport(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := newFlagSet("export")
+	out := fs.String("out", "", "file to write the snapshot to (default stdout)")
+	format := fs.String("format", "", "format of the snapshot: json or yaml (default by the extension of --out)")
+	contextsFile := fs.String("contexts", "", "JSON or YAML file with a list of evaluation contexts")
+	evalCtx := contextVar(fs)
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 {
+		return fmt.Errorf("%w: export expects no arguments", errUsage)
+	}
+
+	if *format == "" {
+		*format = adapter.SnapshotFormatFromPath(*out)
+	}
+
+	if *format != adapter.SnapshotFormatJSON && *format != adapter.SnapshotFormatYAML {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+
+	var contexts []eval.Context
+	if *contextsFile != "" {
+		if contexts, err = readContexts(*contextsFile); err != nil {
+			return err
+		}
+	}
+
+	if len(evalCtx) > 0 {
+		contexts = append(contexts, evalCtx)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	s, err := client.Snapshot(ctx, contexts...)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return adapter.WriteSnapshot(stdout, s, *format)
+	}
+
+	return writeSnapshotFile(*out, s, *format)
+}
+
+func runImport(ctx context.Context, args []string, _ io.Writer) error {
+	fs := newFlagSet("import")
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return fmt.Errorf("%w: import expects a snapshot and a file", errUsage)
+	}
+	src, dst := positional[0], positional[1]
+
+	s, err := readSnapshotFile(src)
+	if err != nil {
+		return err
+	}
+
+	// merge into the existing snapshot, so that several exports can be
+	// combined into one file
+	existing, err := readSnapshotFile(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	existing.Merge(s)
+
+	if err := writeSnapshotFile(dst, existing, adapter.SnapshotFormatFromPath(dst)); err != nil {
+		return err
+	}
+
+	// make sure the file can be served
+	return adapter.NewFile(dst).Init(ctx)
+}
+
+func readSnapshotFile(path string) (adapter.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return adapter.Snapshot{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return adapter.ReadSnapshot(f, adapter.SnapshotFormatFromPath(path))
+}
+
+// writeSnapshotFile writes a snapshot to a temporary file in the directory
+// of path and renames it to path, so that a failed write never leaves a
+// truncated snapshot behind for the adapters that serve it.
+func writeSnapshotFile(path string, s adapter.Snapshot, format string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if err := writeSnapshotTo(f, s, format); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func writeSnapshotTo(f *os.File, s adapter.Snapshot, format string) error {
+	// CreateTemp creates files readable only by the owner
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+
+	if err := adapter.WriteSnapshot(f, s, format); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// readContexts reads a list of evaluation contexts from a JSON or YAML file.
+func readContexts(path string) ([]eval.Context, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []eval.Context
+	if adapter.SnapshotFormatFromPath(path) == adapter.SnapshotFormatYAML {
+		err = yaml.Unmarshal(b, &contexts)
+	} else {
+		err = json.Unmarshal(b, &contexts)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode contexts: %w", err)
+	}
+
+	return contexts, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the flags of a generated package.
+type Manifest struct {
+	Package string `json:"package"`
+	Flags   []Flag `json:"flags"`
+}
+
+// Flag describes a flag of a manifest.
+type Flag struct {
+	Key         string      `json:"key"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+}
+
+// methods maps flag types to the Go type and the client method that
+// evaluates them.
+var methods = map[string]struct {
+	goType string
+	method string
+}{
+	"bool":   {"bool", "GetBool"},
+	"int64":  {"int64", "GetInt64"},
+	"string": {"string", "GetString"},
+	"object": {"interface{}", "GetObject"},
+}
+
+// readManifest reads a manifest in YAML or JSON format. YAML is converted to
+// JSON, so that values have the same types in both formats.
+func readManifest(path string) (Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return Manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if b, err = json.Marshal(v); err != nil {
+		return Manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+type templateFlag struct {
+	Key         string
+	Name        string
+	GoType      string
+	Method      string
+	Default     string
+	Description []string
+}
+
+var tmpl = template.Must(template.New("flags").Parse(`// Code generated by kickplangen. DO NOT EDIT.
+
+// Package {{.Package}} provides typed accessors of Kickplan flags.
+package {{.Package}}
+
+import (
+	"context"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Keys of the flags.
+const (
+{{- range .Flags}}
+	{{.Name}}Key = {{printf "%q" .Key}}
+{{- end}}
+)
+{{range .Flags}}
+// {{.Name}} returns the value of the {{.Key}} flag, or its default if the flag
+// cannot be evaluated. Evaluation errors are reported to the hooks of the client.
+{{- if .Description}}
+//
+{{- range .Description}}
+// {{.}}
+{{- end}}
+{{- end}}
+func {{.Name}}(ctx context.Context, client *kickplan.Client, evalCtx eval.Context, opt ...kickplan.EvalOption) {{.GoType}} {
+	v, _ := client.{{.Method}}(ctx, {{.Name}}Key, {{.Default}}, evalCtx, opt...)
+	return v
+}
+{{end}}`))
+
+// generate returns the formatted source of the package described by m.
+func generate(m Manifest) ([]byte, error) {
+	if !token.IsIdentifier(m.Package) {
+		return nil, fmt.Errorf("invalid package name %q", m.Package)
+	}
+
+	names := make(map[string]string)
+	keys := make(map[string]bool)
+	flags := make([]templateFlag, 0, len(m.Flags))
+	for _, f := range m.Flags {
+		if f.Key == "" {
+			return nil, fmt.Errorf("flag without key")
+		}
+
+		if keys[f.Key] {
+			return nil, fmt.Errorf("flag %q is defined twice", f.Key)
+		}
+		keys[f.Key] = true
+
+		t, ok := methods[f.Type]
+		if !ok {
+			return nil, fmt.Errorf("flag %q has unknown type %q", f.Key, f.Type)
+		}
+
+		name := f.Name
+		if name == "" {
+			name = goName(f.Key)
+		}
+
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return nil, fmt.Errorf("flag %q has invalid name %q", f.Key, name)
+		}
+
+		// every flag declares a function and a key constant
+		for _, ident := range []string{name, name + "Key"} {
+			if other, ok := names[ident]; ok {
+				return nil, fmt.Errorf("flags %q and %q both declare %s", other, f.Key, ident)
+			}
+			names[ident] = f.Key
+		}
+
+		def, err := goDefault(f.Type, f.Default)
+		if err != nil {
+			return nil, fmt.Errorf("flag %q: %w", f.Key, err)
+		}
+
+		var description []string
+		if d := strings.TrimSpace(f.Description); d != "" {
+			description = strings.Split(d, "\n")
+		}
+
+		flags = append(flags, templateFlag{
+			Key:         f.Key,
+			Name:        name,
+			GoType:      t.goType,
+			Method:      t.method,
+			Default:     def,
+			Description: description,
+		})
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Package string
+		Flags   []templateFlag
+	}{m.Package, flags}); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+// goName converts a flag key like "new-dashboard" to an exported Go name
+// like "NewDashboard".
+func goName(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "Flag" + name
+	}
+
+	return name
+}
+
+// goDefault returns the default value of a flag as Go source.
+func goDefault(typ string, v interface{}) (string, error) {
+	switch typ {
+	case "bool":
+		if v == nil {
+			return "false", nil
+		}
+
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("default %v is not a bool", v)
+		}
+
+		return strconv.FormatBool(b), nil
+	case "int64":
+		if v == nil {
+			return "0", nil
+		}
+
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return "", fmt.Errorf("default %v is not an int64", v)
+		}
+
+		return strconv.FormatInt(int64(f), 10), nil
+	case "string":
+		if v == nil {
+			return `""`, nil
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("default %v is not a string", v)
+		}
+
+		return strconv.Quote(s), nil
+	default:
+		return goLiteral(v)
+	}
+}
+
+// goLiteral returns a decoded JSON value as Go source. Numbers are float64
+// literals, like the values of evaluated object flags.
+func goLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "nil", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")", nil
+	case string:
+		return strconv.Quote(v), nil
+	case []interface{}:
+		elems := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := goLiteral(e)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, s)
+		}
+
+		return "[]interface{}{" + strings.Join(elems, ", ") + "}", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		elems := make([]string, 0, len(v))
+		for _, k := range keys {
+			s, err := goLiteral(v[k])
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, strconv.Quote(k)+": "+s)
+		}
+
+		return "map[string]interface{}{" + strings.Join(elems, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("default %v has unsupported type %T", v, v)
+	}
+}
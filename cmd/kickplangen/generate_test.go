@@ -0,0 +1,106 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testManifest = `package: flags
+flags:
+  - key: new-dashboard
+    type: bool
+    default: true
+    description: Enables the new dashboard.
+  - key: seats
+    type: int64
+    default: 5
+  - key: plan
+    name: PlanName
+    type: string
+    default: free
+  - key: limits
+    type: object
+    default:
+      projects: 3
+`
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	if err := os.WriteFile(path, []byte(testManifest), 0o644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := readManifest(path)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+
+	src, err := generate(m)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	for _, s := range []string{
+		`NewDashboardKey = "new-dashboard"`,
+		`func NewDashboard(ctx context.Context, client *kickplan.Client, evalCtx eval.Context, opt ...kickplan.EvalOption) bool {`,
+		`client.GetBool(ctx, NewDashboardKey, true, evalCtx, opt...)`,
+		`// Enables the new dashboard.`,
+		`client.GetInt64(ctx, SeatsKey, 5, evalCtx, opt...)`,
+		`client.GetString(ctx, PlanNameKey, "free", evalCtx, opt...)`,
+		`func Limits(ctx context.Context, client *kickplan.Client, evalCtx eval.Context, opt ...kickplan.EvalOption) interface{} {`,
+		`client.GetObject(ctx, LimitsKey, map[string]interface{}{"projects": float64(3)}, evalCtx, opt...)`,
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Fatalf("expected generated code to contain %q, got:\n%s", s, src)
+		}
+	}
+
+	// the generated code must compile against the client
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "flags_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("failed to parse generated code: %v", err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("flags", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("failed to type check generated code: %v\n%s", err, src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, m := range []Manifest{
+		{Package: "flags", Flags: []Flag{{Key: "a", Type: "float"}}},
+		{Package: "flags", Flags: []Flag{{Key: "a", Type: "bool", Default: "yes"}}},
+		{Package: "flags", Flags: []Flag{{Key: "a", Type: "int64", Default: 1.5}}},
+		{Package: "flags", Flags: []Flag{{Key: "a", Type: "bool"}, {Key: "a", Type: "bool"}}},
+		{Package: "flags", Flags: []Flag{{Key: "my-flag", Type: "bool"}, {Key: "my_flag", Type: "bool"}}},
+		{Package: "flags", Flags: []Flag{{Key: "seats", Type: "int64"}, {Key: "seats-key", Type: "int64"}}},
+		{Package: "flags", Flags: []Flag{{Key: "seats-key", Type: "int64"}, {Key: "seats", Type: "int64"}}},
+		{Package: "my-flags", Flags: []Flag{{Key: "a", Type: "bool"}}},
+	} {
+		if _, err := generate(m); err == nil {
+			t.Fatalf("expected manifest %+v to fail", m)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"new-dashboard":  "NewDashboard",
+		"api_rate_limit": "ApiRateLimit",
+		"beta.v2":        "BetaV2",
+		"2fa":            "Flag2fa",
+	} {
+		if name := goName(key); name != expected {
+			t.Fatalf("expected name of %q to be %s, got %s", key, expected, name)
+		}
+	}
+}
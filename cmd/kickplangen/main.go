@@ -0,0 +1,62 @@
+// Command kickplangen generates typed accessors of flags from a manifest.
+//
+// A manifest lists the flags with their key, type, default value and
+// description in YAML or JSON:
+//
+//	package: flags
+//	flags:
+//	  - key: new-dashboard
+//	    type: bool
+//	    default: false
+//	    description: Enables the new dashboard.
+//	  - key: seats
+//	    type: int64
+//	    default: 5
+//
+// The generated package has a constant with the key and a function that
+// evaluates each flag with its default:
+//
+//	const NewDashboardKey = "new-dashboard"
+//
+//	func NewDashboard(ctx context.Context, client *kickplan.Client, evalCtx eval.Context, opt ...kickplan.EvalOption) bool
+//
+// Types are bool, int64, string and object. The function name is derived
+// from the key unless the flag has a name. Use it with go generate:
+//
+//	//go:generate go run github.com/kickplan/sdk-go/cmd/kickplangen -manifest flags.yaml -out flags_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "flags.yaml", "path of the flag manifest")
+	out := flag.String("out", "flags_gen.go", "path of the generated file")
+	pkg := flag.String("package", "", "name of the generated package (default from the manifest)")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("kickplangen: ")
+
+	m, err := readManifest(*manifestPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *pkg != "" {
+		m.Package = *pkg
+	}
+
+	src, err := generate(m)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(fmt.Errorf("failed to write %s: %w", *out, err))
+	}
+}
@@ -0,0 +1,21 @@
+// Command kickplanvet finds the Kickplan flags evaluated by Go code.
+//
+// Run it standalone:
+//
+//	kickplanvet ./...
+//	kickplanvet -usages ./...
+//
+// or with go vet:
+//
+//	go vet -vettool=$(which kickplanvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/kickplan/sdk-go/kickplanvet"
+)
+
+func main() {
+	singlechecker.Main(kickplanvet.Analyzer)
+}
@@ -1,5 +1,44 @@
 // Package eval provides the context for the evaluation of flags
 package eval
 
+import "context"
+
 // Context is a map that represents the context of an evaluation.
 type Context map[string]interface{}
+
+type contextKey struct{}
+
+// Merge returns a new Context with the attributes of all given contexts.
+// Contexts are applied in order, so attributes of later contexts take
+// precedence over attributes of earlier ones. Merge returns nil if none of
+// the contexts has attributes.
+func Merge(contexts ...Context) Context {
+	var merged Context
+	for _, c := range contexts {
+		for k, v := range c {
+			if merged == nil {
+				merged = make(Context)
+			}
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
+
+// NewContext returns a copy of ctx that carries the evaluation context
+// evalCtx. It replaces any evaluation context already stored in ctx; use
+// Merge with FromContext to extend it instead.
+func NewContext(ctx context.Context, evalCtx Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, evalCtx)
+}
+
+// FromContext returns the evaluation context stored in ctx, if any.
+func FromContext(ctx context.Context) Context {
+	if ctx == nil {
+		return nil
+	}
+
+	evalCtx, _ := ctx.Value(contextKey{}).(Context)
+	return evalCtx
+}
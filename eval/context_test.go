@@ -0,0 +1,42 @@
+package eval
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	merged := Merge(
+		Context{"env": "prod", "region": "eu"},
+		nil,
+		Context{"region": "us", "account_id": "account"},
+	)
+
+	expected := Context{"env": "prod", "region": "us", "account_id": "account"}
+	if len(merged) != len(expected) {
+		t.Fatalf("expected merged context to be %v, got %v", expected, merged)
+	}
+
+	for k, v := range expected {
+		if merged[k] != v {
+			t.Fatalf("expected merged context %s to be %v, got %v", k, v, merged[k])
+		}
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if merged := Merge(nil, Context{}); merged != nil {
+		t.Fatalf("expected merged context to be nil, got %v", merged)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	if evalCtx := FromContext(context.TODO()); evalCtx != nil {
+		t.Fatalf("expected no evaluation context, got %v", evalCtx)
+	}
+
+	ctx := NewContext(context.TODO(), Context{"account_id": "account"})
+	if evalCtx := FromContext(ctx); evalCtx["account_id"] != "account" {
+		t.Fatalf("expected evaluation context account_id to be account, got %v", evalCtx["account_id"])
+	}
+}
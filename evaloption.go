@@ -0,0 +1,86 @@
+package kickplan
+
+import (
+	"context"
+	"time"
+
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+// EvalOption is a function that configures a single flag evaluation.
+type EvalOption func(*evalOptions)
+
+type evalOptions struct {
+	timeout time.Duration
+	call    adapter.CallOptions
+	hooks   []Hook
+}
+
+// WithCallTimeout cancels the evaluation if it takes longer than d.
+func WithCallTimeout(d time.Duration) EvalOption {
+	return func(o *evalOptions) {
+		o.timeout = d
+	}
+}
+
+// BypassCache evaluates the flag without serving it from or storing it in
+// caches, e.g. adapter.WithCache.
+func BypassCache() EvalOption {
+	return func(o *evalOptions) {
+		o.call.BypassCache = true
+	}
+}
+
+// RequireFresh evaluates the flag at its source instead of serving a cached
+// value, e.g. right after changing it. The fresh value is cached.
+func RequireFresh() EvalOption {
+	return func(o *evalOptions) {
+		o.call.RequireFresh = true
+	}
+}
+
+// WithCallHook adds a hook that is notified after the evaluation, after the
+// hooks of the client.
+func WithCallHook(h Hook) EvalOption {
+	return func(o *evalOptions) {
+		o.hooks = append(o.hooks, h)
+	}
+}
+
+// WithTags annotates the evaluation for telemetry. Tags are passed to the
+// adapters with adapter.CallOptions and to hooks with EvaluationDetails.
+// Tags of several options are merged.
+func WithTags(tags map[string]string) EvalOption {
+	return func(o *evalOptions) {
+		if o.call.Tags == nil {
+			o.call.Tags = make(map[string]string, len(tags))
+		}
+
+		for k, v := range tags {
+			o.call.Tags[k] = v
+		}
+	}
+}
+
+func newEvalOptions(opt []EvalOption) evalOptions {
+	var o evalOptions
+	for _, fn := range opt {
+		fn(&o)
+	}
+
+	return o
+}
+
+// context returns the context the adapter is called with and a function
+// that releases it.
+func (o evalOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.call.BypassCache || o.call.RequireFresh || len(o.call.Tags) > 0 {
+		ctx = adapter.WithCallOptions(ctx, o.call)
+	}
+
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+
+	return ctx, func() {}
+}
@@ -28,6 +28,7 @@ func main() {
 			adapter.NewKickplan(endpoint, token, userAgent, timeout),
 		),
 	)
+	defer func() { _ = client.Close(ctx) }()
 
 	const flag = "my-flag"
 
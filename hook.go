@@ -0,0 +1,146 @@
+package kickplan
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Error codes reported in evaluation details for errors without a code.
+const (
+	errorCodeFlagNotFound = "FLAG_NOT_FOUND"
+	errorCodeTypeMismatch = "TYPE_MISMATCH"
+	errorCodeClientClosed = "CLIENT_CLOSED"
+	errorCodeGeneral      = "GENERAL"
+)
+
+// EvaluationDetails describes the outcome of a flag evaluation.
+type EvaluationDetails struct {
+	Flag        string
+	Value       interface{}
+	Variant     string
+	Reason      string
+	ErrorCode   string
+	Adapter     string
+	EvalContext eval.Context
+
+	// Tags are the tags passed to the evaluation with WithTags.
+	Tags map[string]string
+}
+
+// Hook is notified after every flag evaluation made by a Client.
+type Hook interface {
+	AfterEvaluation(ctx context.Context, details EvaluationDetails, err error)
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as hooks.
+type HookFunc func(ctx context.Context, details EvaluationDetails, err error)
+
+// AfterEvaluation calls f(ctx, details, err).
+func (f HookFunc) AfterEvaluation(ctx context.Context, details EvaluationDetails, err error) {
+	f(ctx, details, err)
+}
+
+// WithHook adds a hook that is notified after every flag evaluation.
+// Hooks are called in the order they were added.
+func WithHook(h Hook) Option {
+	return func(c *Client) error {
+		c.hooks = append(c.hooks, h)
+		return nil
+	}
+}
+
+// evaluate resolves a flag with the merged evaluation context and notifies
+// the hooks of the client about the outcome.
+func evaluate[T any](
+	ctx context.Context,
+	c *Client,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	resolve func(adapter.Adapter, context.Context, string, T, eval.Context) (T, error),
+	opt []EvalOption,
+) (T, error) {
+	if len(c.hooks) == 0 && len(opt) == 0 {
+		value := defaultValue
+		err := c.call(func(a adapter.Adapter) error {
+			var err error
+			value, err = resolve(a, ctx, flag, defaultValue, c.mergeEvalContext(ctx, evalCtx))
+			return err
+		})
+
+		return value, err
+	}
+
+	value, _, err := evaluateDetails(ctx, c, flag, defaultValue, evalCtx, resolve, opt)
+	return value, err
+}
+
+// evaluateDetails is like evaluate, but also returns the details of the
+// evaluation.
+func evaluateDetails[T any](
+	ctx context.Context,
+	c *Client,
+	flag string,
+	defaultValue T,
+	evalCtx eval.Context,
+	resolve func(adapter.Adapter, context.Context, string, T, eval.Context) (T, error),
+	opt []EvalOption,
+) (T, EvaluationDetails, error) {
+	o := newEvalOptions(opt)
+	evalCtx = c.mergeEvalContext(ctx, evalCtx)
+
+	resolveCtx, cancel := o.context(ctx)
+	defer cancel()
+
+	var d adapter.Details
+	value := defaultValue
+	err := c.call(func(a adapter.Adapter) error {
+		var err error
+		value, err = resolve(a, adapter.WithDetails(resolveCtx, &d), flag, defaultValue, evalCtx)
+		return err
+	})
+
+	details := EvaluationDetails{
+		Flag:        flag,
+		Value:       value,
+		Variant:     d.Variant,
+		Reason:      d.Reason,
+		ErrorCode:   d.ErrorCode,
+		Adapter:     d.Adapter,
+		EvalContext: evalCtx,
+		Tags:        o.call.Tags,
+	}
+
+	if err != nil {
+		details.Reason = adapter.ReasonError
+		if details.ErrorCode == "" {
+			details.ErrorCode = errorCode(err)
+		}
+	}
+
+	for _, h := range c.hooks {
+		h.AfterEvaluation(ctx, details, err)
+	}
+
+	for _, h := range o.hooks {
+		h.AfterEvaluation(ctx, details, err)
+	}
+
+	return value, details, err
+}
+
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, adapter.ErrFlagNotFound):
+		return errorCodeFlagNotFound
+	case errors.Is(err, adapter.ErrTypeMismatch):
+		return errorCodeTypeMismatch
+	case errors.Is(err, ErrClientClosed):
+		return errorCodeClientClosed
+	default:
+		return errorCodeGeneral
+	}
+}
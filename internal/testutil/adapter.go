@@ -0,0 +1,67 @@
+// Package testutil provides fake adapters shared by the tests of this module
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Verify that ContextAdapter implements adapter.Adapter and that
+// LoadingAdapter implements adapter.Adapter and adapter.StatusReporter.
+var (
+	_ adapter.Adapter        = (*ContextAdapter)(nil)
+	_ adapter.Adapter        = (*LoadingAdapter)(nil)
+	_ adapter.StatusReporter = (*LoadingAdapter)(nil)
+)
+
+// ContextAdapter is an in-memory adapter that records the evaluation context
+// of the last boolean evaluation.
+type ContextAdapter struct {
+	*adapter.InMemory
+
+	mu      sync.Mutex
+	evalCtx eval.Context
+}
+
+// NewContextAdapter returns a new ContextAdapter without flags.
+func NewContextAdapter() *ContextAdapter {
+	return &ContextAdapter{InMemory: adapter.NewInMemory()}
+}
+
+// BooleanEvaluation records the evaluation context and returns the value of
+// a boolean flag.
+func (a *ContextAdapter) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	a.mu.Lock()
+	a.evalCtx = evalCtx
+	a.mu.Unlock()
+
+	return a.InMemory.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+}
+
+// EvalContext returns the evaluation context of the last boolean evaluation.
+func (a *ContextAdapter) EvalContext() eval.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.evalCtx
+}
+
+// LoadingAdapter is an in-memory adapter whose status is set by the test
+// with SetStatus. It starts as not ready.
+type LoadingAdapter struct {
+	*adapter.InMemory
+	adapter.StatusTracker
+}
+
+// NewLoadingAdapter returns a new LoadingAdapter without flags.
+func NewLoadingAdapter() *LoadingAdapter {
+	return &LoadingAdapter{InMemory: adapter.NewInMemory()}
+}
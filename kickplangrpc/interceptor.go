@@ -0,0 +1,183 @@
+// Package kickplangrpc propagates the evaluation context between gRPC services
+package kickplangrpc
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// MetadataKey is the gRPC metadata key that carries the evaluation context.
+// The context is encoded as JSON, so numbers are restored as float64.
+const MetadataKey = "kickplan-eval-context-bin"
+
+// Option is a function that configures the interceptors.
+type Option func(*config)
+
+type config struct {
+	attributes map[string]struct{}
+}
+
+// WithAttributes limits propagation to the given attributes of the
+// evaluation context. By default all attributes are propagated.
+func WithAttributes(keys ...string) Option {
+	return func(c *config) {
+		c.attributes = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			c.attributes[k] = struct{}{}
+		}
+	}
+}
+
+func newConfig(opt []Option) *config {
+	c := new(config)
+	for _, o := range opt {
+		o(c)
+	}
+
+	return c
+}
+
+// UnaryClientInterceptor returns an interceptor that sends the evaluation
+// context stored in the call context to the server.
+func UnaryClientInterceptor(opt ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(opt)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(c.outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that sends the evaluation
+// context stored in the stream context to the server.
+func StreamClientInterceptor(opt ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(opt)
+
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(c.outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that restores the evaluation
+// context sent by the client on the handler context.
+//
+// The metadata is set by the client and is not authenticated, so any client
+// can send any attributes, e.g. the account_id or plan that decide which
+// features it gets. Attributes already in the server context, e.g. set by an
+// authentication interceptor that runs first, take precedence over the
+// received ones. Use WithAttributes to accept only the attributes that are
+// safe to take from the client, or install the interceptor only on services
+// called by trusted clients.
+func UnaryServerInterceptor(opt ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opt)
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(c.incoming(ctx), req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that restores the evaluation
+// context sent by the client on the stream context. The metadata is not
+// authenticated, see UnaryServerInterceptor.
+func StreamServerInterceptor(opt ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opt)
+
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: c.incoming(ss.Context())})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (c *config) outgoing(ctx context.Context) context.Context {
+	evalCtx := c.filter(eval.FromContext(ctx))
+	if len(evalCtx) == 0 {
+		return ctx
+	}
+
+	b, err := json.Marshal(evalCtx)
+	if err != nil {
+		log.Printf("WARN failed to encode evaluation context: %v", err)
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, string(b))
+}
+
+func (c *config) incoming(ctx context.Context) context.Context {
+	values := metadata.ValueFromIncomingContext(ctx, MetadataKey)
+	if len(values) == 0 {
+		return ctx
+	}
+
+	var evalCtx eval.Context
+	for _, v := range values {
+		var received eval.Context
+		if err := json.Unmarshal([]byte(v), &received); err != nil {
+			log.Printf("WARN failed to decode evaluation context: %v", err)
+			continue
+		}
+
+		evalCtx = eval.Merge(evalCtx, c.filter(received))
+	}
+
+	// the server context is merged last, so that clients cannot override
+	// the attributes the server set
+	return eval.NewContext(ctx, eval.Merge(evalCtx, eval.FromContext(ctx)))
+}
+
+func (c *config) filter(evalCtx eval.Context) eval.Context {
+	if c.attributes == nil {
+		return evalCtx
+	}
+
+	var filtered eval.Context
+	for k, v := range evalCtx {
+		if _, ok := c.attributes[k]; !ok {
+			continue
+		}
+
+		if filtered == nil {
+			filtered = make(eval.Context)
+		}
+		filtered[k] = v
+	}
+
+	return filtered
+}
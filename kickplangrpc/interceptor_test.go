@@ -0,0 +1,123 @@
+package kickplangrpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// roundTrip sends ctx through the client interceptor and returns the
+// incoming context the server would see.
+func roundTrip(t *testing.T, ctx context.Context, opt ...Option) context.Context {
+	t.Helper()
+
+	var outgoing context.Context
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		outgoing = ctx
+		return nil
+	}
+
+	if err := UnaryClientInterceptor(opt...)(ctx, "/service/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("failed to invoke: %v", err)
+	}
+
+	md, _ := metadata.FromOutgoingContext(outgoing)
+	return metadata.NewIncomingContext(context.TODO(), md)
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	ctx := eval.NewContext(context.TODO(), eval.Context{"account_id": "account", "seats": 5})
+
+	var evalCtx eval.Context
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		evalCtx = eval.FromContext(ctx)
+		return nil, nil
+	}
+
+	if _, err := UnaryServerInterceptor()(roundTrip(t, ctx), nil, nil, handler); err != nil {
+		t.Fatalf("failed to handle: %v", err)
+	}
+
+	if evalCtx["account_id"] != "account" {
+		t.Fatalf("expected evaluation context account_id to be account, got %v", evalCtx["account_id"])
+	}
+
+	if evalCtx["seats"] != float64(5) {
+		t.Fatalf("expected evaluation context seats to be 5, got %v", evalCtx["seats"])
+	}
+}
+
+func TestWithAttributes(t *testing.T) {
+	ctx := eval.NewContext(context.TODO(), eval.Context{"account_id": "account", "email": "user@example.com"})
+
+	var evalCtx eval.Context
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		evalCtx = eval.FromContext(ctx)
+		return nil, nil
+	}
+
+	incoming := roundTrip(t, ctx, WithAttributes("account_id"))
+	if _, err := UnaryServerInterceptor()(incoming, nil, nil, handler); err != nil {
+		t.Fatalf("failed to handle: %v", err)
+	}
+
+	if len(evalCtx) != 1 || evalCtx["account_id"] != "account" {
+		t.Fatalf("expected evaluation context to only have account_id, got %v", evalCtx)
+	}
+}
+
+func TestServerContextPrecedence(t *testing.T) {
+	ctx := eval.NewContext(context.TODO(), eval.Context{"account_id": "spoofed", "seats": 5})
+
+	var evalCtx eval.Context
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		evalCtx = eval.FromContext(ctx)
+		return nil, nil
+	}
+
+	// the account set by the server, e.g. from the credentials of the call
+	incoming := eval.NewContext(roundTrip(t, ctx), eval.Context{"account_id": "account"})
+	if _, err := UnaryServerInterceptor()(incoming, nil, nil, handler); err != nil {
+		t.Fatalf("failed to handle: %v", err)
+	}
+
+	if evalCtx["account_id"] != "account" {
+		t.Fatalf("expected the server account_id to take precedence, got %v", evalCtx["account_id"])
+	}
+
+	if evalCtx["seats"] != float64(5) {
+		t.Fatalf("expected evaluation context seats to be 5, got %v", evalCtx["seats"])
+	}
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	ctx := eval.NewContext(context.TODO(), eval.Context{"account_id": "account"})
+
+	var evalCtx eval.Context
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		evalCtx = eval.FromContext(ss.Context())
+		return nil
+	}
+
+	ss := &mockServerStream{ctx: roundTrip(t, ctx)}
+	if err := StreamServerInterceptor()(nil, ss, nil, handler); err != nil {
+		t.Fatalf("failed to handle: %v", err)
+	}
+
+	if evalCtx["account_id"] != "account" {
+		t.Fatalf("expected evaluation context account_id to be account, got %v", evalCtx["account_id"])
+	}
+}
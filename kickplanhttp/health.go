@@ -0,0 +1,42 @@
+package kickplanhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	kickplan "github.com/kickplan/sdk-go"
+)
+
+// health is the response of the health handler.
+type health struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthHandler returns a handler that reports the status of the client,
+// e.g. for Kubernetes readiness probes. It responds with 200 OK while the
+// client serves flags, i.e. its adapter is ready or stale, and with 503
+// Service Unavailable otherwise. The body is a JSON object with the status
+// and the error that caused it, if any:
+//
+//	{"status": "stale", "error": "failed to open snapshot: ..."}
+func HealthHandler(client *kickplan.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s, err := client.Status()
+
+		h := health{Status: s.String()}
+		if err != nil {
+			h.Error = err.Error()
+		}
+
+		code := http.StatusOK
+		if !s.Serving() {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
@@ -0,0 +1,81 @@
+package kickplanhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/internal/testutil"
+)
+
+func TestHealthHandler(t *testing.T) {
+	loading := testutil.NewLoadingAdapter()
+	handler := HealthHandler(kickplan.NewClient(kickplan.WithAdapter(loading)))
+
+	for _, tc := range []struct {
+		status adapter.Status
+		err    error
+		code   int
+		body   health
+	}{
+		{adapter.StatusNotReady, nil, http.StatusServiceUnavailable, health{Status: "not_ready"}},
+		{adapter.StatusReady, nil, http.StatusOK, health{Status: "ready"}},
+		{adapter.StatusStale, errors.New("refresh failed"), http.StatusOK, health{Status: "stale", Error: "refresh failed"}},
+		{adapter.StatusError, errors.New("load failed"), http.StatusServiceUnavailable, health{Status: "error", Error: "load failed"}},
+	} {
+		loading.SetStatus(tc.status, tc.err)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != tc.code {
+			t.Fatalf("expected status code %d for %s, got %d", tc.code, tc.status, rec.Code)
+		}
+
+		var body health
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+
+		if body != tc.body {
+			t.Fatalf("expected body %+v, got %+v", tc.body, body)
+		}
+	}
+}
+
+func TestHealthHandlerUnavailable(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	client := kickplan.NewClient(kickplan.WithAdapter(adapter.NewFallback(
+		adapter.FallbackLink{Adapter: adapter.NewKickplan(api.URL, "token", "", ""), Policy: adapter.FallThroughOnError},
+		adapter.FallbackLink{Adapter: adapter.NewInMemory(), IgnoreStatus: true},
+	)))
+
+	if err := client.Init(context.TODO()); err != nil {
+		t.Fatalf("failed to init client: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	HealthHandler(client).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	var body health
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+
+	if body.Status != "error" || body.Error == "" {
+		t.Fatalf("expected status error with an error, got %+v", body)
+	}
+}
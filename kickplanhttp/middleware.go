@@ -0,0 +1,31 @@
+// Package kickplanhttp provides net/http integration for the Kickplan client
+package kickplanhttp
+
+import (
+	"net/http"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// ExtractFunc returns the evaluation context for a request, e.g. the
+// attributes of the authenticated account.
+type ExtractFunc func(r *http.Request) eval.Context
+
+// Middleware returns a middleware that stores the evaluation context returned
+// by extract on the request context. The attributes are merged over any
+// evaluation context already stored there, so the client picks them up
+// for every evaluation made while handling the request.
+func Middleware(extract ExtractFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			evalCtx := extract(r)
+			if len(evalCtx) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := eval.NewContext(r.Context(), eval.Merge(eval.FromContext(r.Context()), evalCtx))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package kickplanhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/eval"
+	"github.com/kickplan/sdk-go/internal/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	middleware := Middleware(func(r *http.Request) eval.Context {
+		return eval.Context{"account_id": r.Header.Get("X-Account-ID")}
+	})
+
+	var evalCtx eval.Context
+	handler := middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		evalCtx = eval.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(eval.NewContext(req.Context(), eval.Context{"region": "eu"}))
+	req.Header.Set("X-Account-ID", "account")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if evalCtx["account_id"] != "account" {
+		t.Fatalf("expected evaluation context account_id to be account, got %v", evalCtx["account_id"])
+	}
+
+	if evalCtx["region"] != "eu" {
+		t.Fatalf("expected evaluation context region to be eu, got %v", evalCtx["region"])
+	}
+}
+
+func TestMiddlewareClient(t *testing.T) {
+	a := testutil.NewContextAdapter()
+	client := kickplan.NewClient(kickplan.WithAdapter(a))
+
+	middleware := Middleware(func(r *http.Request) eval.Context {
+		return eval.Context{"account_id": r.Header.Get("X-Account-ID")}
+	})
+
+	handler := middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if _, err := client.GetBool(r.Context(), "my-flag", false, nil); err != nil {
+			t.Fatalf("failed to get flag: %v", err)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Account-ID", "account")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if a.EvalContext()["account_id"] != "account" {
+		t.Fatalf("expected evaluation context account_id to be account, got %v", a.EvalContext()["account_id"])
+	}
+}
@@ -0,0 +1,186 @@
+// Package kickplanotel provides OpenTelemetry tracing for the Kickplan client
+package kickplanotel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/kickplan/sdk-go/kickplanotel"
+
+// DefaultProviderName is the default provider name reported in feature_flag events.
+const DefaultProviderName = "Kickplan"
+
+// Attributes of feature_flag events as defined by the OpenTelemetry semantic
+// conventions for feature flags.
+const (
+	eventName = "feature_flag"
+
+	keyFlagKey      = attribute.Key("feature_flag.key")
+	keyProviderName = attribute.Key("feature_flag.provider_name")
+	keyVariant      = attribute.Key("feature_flag.variant")
+	keyReason       = attribute.Key("feature_flag.evaluation.reason")
+	keyErrorMessage = attribute.Key("feature_flag.evaluation.error.message")
+
+	// tagPrefix prefixes the keys of tags passed with kickplan.WithTags.
+	tagPrefix = "kickplan.tag."
+)
+
+// Option is a function that configures the instrumentation.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	providerName   string
+}
+
+// WithTracerProvider sets the tracer provider. Defaults to the global one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithPropagators sets the propagators used to inject the span context into
+// API requests. Defaults to the global ones.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = p
+	}
+}
+
+// WithProviderName sets the provider name reported in feature_flag events.
+// Defaults to DefaultProviderName.
+func WithProviderName(name string) Option {
+	return func(c *config) {
+		c.providerName = name
+	}
+}
+
+func newConfig(opt []Option) *config {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+		providerName:   DefaultProviderName,
+	}
+	for _, o := range opt {
+		o(c)
+	}
+
+	return c
+}
+
+// RequestHook returns a hook for the Kickplan adapter that creates a client
+// span for every API request. Pass it to adapter.WithRequestHook.
+func RequestHook(opt ...Option) adapter.RequestHook {
+	c := newConfig(opt)
+	tracer := c.tracerProvider.Tracer(ScopeName)
+
+	return func(ctx context.Context, route string, req *http.Request) (context.Context, func(*http.Response, error)) {
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.String()),
+			attribute.String("url.template", route),
+		}
+
+		host, port, err := net.SplitHostPort(req.URL.Host)
+		if err != nil {
+			host = req.URL.Hostname()
+		}
+		attrs = append(attrs, attribute.String("server.address", host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, attribute.Int("server.port", p))
+		}
+
+		ctx, span := tracer.Start(ctx, req.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+		)
+		c.propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+		return ctx, func(resp *http.Response, err error) {
+			defer span.End()
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return
+			}
+
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetAttributes(attribute.String("error.type", strconv.Itoa(resp.StatusCode)))
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+		}
+	}
+}
+
+// Verify that Hook implements kickplan.Hook.
+var _ kickplan.Hook = (*Hook)(nil)
+
+// Hook records a feature_flag event on the span stored in the context of
+// every evaluation.
+type Hook struct {
+	providerName string
+}
+
+// NewHook returns a new Hook. Add it to the client with kickplan.WithHook.
+func NewHook(opt ...Option) *Hook {
+	return &Hook{providerName: newConfig(opt).providerName}
+}
+
+// AfterEvaluation records a feature_flag event with the tags of the
+// evaluation as kickplan.tag.* attributes. It implements kickplan.Hook.
+func (h *Hook) AfterEvaluation(ctx context.Context, details kickplan.EvaluationDetails, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		keyFlagKey.String(details.Flag),
+		keyProviderName.String(h.providerName),
+	}
+
+	if details.Variant != "" {
+		attrs = append(attrs, keyVariant.String(details.Variant))
+	}
+
+	if details.Reason != "" {
+		attrs = append(attrs, keyReason.String(details.Reason))
+	}
+
+	tags := make([]string, 0, len(details.Tags))
+	for k := range details.Tags {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+
+	for _, k := range tags {
+		attrs = append(attrs, attribute.String(tagPrefix+k, details.Tags[k]))
+	}
+
+	if err != nil {
+		attrs = append(attrs,
+			attribute.String("error.type", details.ErrorCode),
+			keyErrorMessage.String(err.Error()),
+		)
+	}
+
+	span.AddEvent(eventName, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,98 @@
+package kickplanotel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+func TestTracing(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		_, _ = w.Write([]byte(`{"key": "flag", "value": true, "variant": "on"}`))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []Option{
+		WithTracerProvider(tp),
+		WithPropagators(propagation.TraceContext{}),
+	}
+
+	client := kickplan.NewClient(
+		kickplan.WithAdapter(adapter.NewKickplan(server.URL, "token", "", "",
+			adapter.WithRequestHook(RequestHook(opts...)),
+		)),
+		kickplan.WithHook(NewHook(opts...)),
+	)
+
+	ctx, parent := tp.Tracer("test").Start(context.TODO(), "parent")
+	if _, err := client.GetBool(ctx, "flag", false, nil, kickplan.WithTags(map[string]string{"route": "/billing"})); err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	request := spans[0]
+	if request.Name() != "POST /features/{flag}" {
+		t.Fatalf("expected request span name to be `POST /features/{flag}`, got %s", request.Name())
+	}
+
+	if request.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("expected request span kind to be client, got %s", request.SpanKind())
+	}
+
+	if request.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected request span to be a child of the parent span")
+	}
+
+	if !hasAttribute(request.Attributes(), attribute.Int("http.response.status_code", http.StatusOK)) {
+		t.Fatalf("expected request span to have status code attribute, got %v", request.Attributes())
+	}
+
+	if traceparent == "" {
+		t.Fatalf("expected request to carry the traceparent header")
+	}
+
+	events := spans[1].Events()
+	if len(events) != 1 || events[0].Name != "feature_flag" {
+		t.Fatalf("expected parent span to have a feature_flag event, got %v", events)
+	}
+
+	for _, attr := range []attribute.KeyValue{
+		attribute.String("feature_flag.key", "flag"),
+		attribute.String("feature_flag.provider_name", DefaultProviderName),
+		attribute.String("feature_flag.variant", "on"),
+		attribute.String("kickplan.tag.route", "/billing"),
+	} {
+		if !hasAttribute(events[0].Attributes, attr) {
+			t.Fatalf("expected feature_flag event to have attribute %v, got %v", attr, events[0].Attributes)
+		}
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, attr attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+// Package kickplanprom provides Prometheus metrics for the Kickplan client
+package kickplanprom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	kickplan "github.com/kickplan/sdk-go"
+)
+
+// Error codes counted for failed Kickplan API requests.
+const (
+	errorCodeRequestFailed = "REQUEST_FAILED"
+	errorCodeHTTPStatus    = "HTTP_%d"
+)
+
+// Verify that Collector implements prometheus.Collector and kickplan.Hook.
+var (
+	_ prometheus.Collector = (*Collector)(nil)
+	_ kickplan.Hook        = (*Collector)(nil)
+)
+
+// Collector collects metrics about the Kickplan client.
+//
+// Register it with a Prometheus registry, add it as a hook to the client
+// with kickplan.WithHook and pass ObserveRequest to the Kickplan adapter
+// with adapter.WithRequestHook.
+//
+// ObserveCache and SetPendingMetricUpdates are meant to be called by caching
+// and buffering adapters, and ObserveShadowComparison by adapter.Shadow.
+type Collector struct {
+	evaluations          *prometheus.CounterVec
+	errors               *prometheus.CounterVec
+	requestDuration      *prometheus.HistogramVec
+	cacheLookups         *prometheus.CounterVec
+	shadowComparisons    *prometheus.CounterVec
+	pendingMetricUpdates prometheus.Gauge
+}
+
+// Option is a function that configures a Collector.
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the namespace of the metrics. Defaults to "kickplan".
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the API request duration histogram.
+// Defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// NewCollector returns a new Collector.
+func NewCollector(opt ...Option) *Collector {
+	o := &options{
+		namespace: "kickplan",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, apply := range opt {
+		apply(o)
+	}
+
+	return &Collector{
+		evaluations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "evaluations_total",
+			Help:      "Total number of flag evaluations.",
+		}, []string{"flag", "variant", "reason"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "errors_total",
+			Help:      "Total number of errors by error code.",
+		}, []string{"code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Duration of Kickplan API requests.",
+			Buckets:   o.buckets,
+		}, []string{"endpoint", "code"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Total number of cache lookups by result.",
+		}, []string{"result"}),
+		shadowComparisons: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "shadow_comparisons_total",
+			Help:      "Total number of comparisons of shadow evaluations by flag and result.",
+		}, []string{"flag", "result"}),
+		pendingMetricUpdates: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pending_metric_updates",
+			Help:      "Number of metric updates waiting to be sent.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.evaluations.Describe(ch)
+	c.errors.Describe(ch)
+	c.requestDuration.Describe(ch)
+	c.cacheLookups.Describe(ch)
+	c.shadowComparisons.Describe(ch)
+	c.pendingMetricUpdates.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.evaluations.Collect(ch)
+	c.errors.Collect(ch)
+	c.requestDuration.Collect(ch)
+	c.cacheLookups.Collect(ch)
+	c.shadowComparisons.Collect(ch)
+	c.pendingMetricUpdates.Collect(ch)
+}
+
+// AfterEvaluation counts a flag evaluation. It implements kickplan.Hook.
+func (c *Collector) AfterEvaluation(_ context.Context, details kickplan.EvaluationDetails, _ error) {
+	c.evaluations.WithLabelValues(details.Flag, details.Variant, details.Reason).Inc()
+	if details.ErrorCode != "" {
+		c.errors.WithLabelValues(details.ErrorCode).Inc()
+	}
+}
+
+// ObserveRequest measures the duration of a Kickplan API request. It is an
+// adapter.RequestHook.
+func (c *Collector) ObserveRequest(
+	ctx context.Context,
+	route string,
+	_ *http.Request,
+) (context.Context, func(*http.Response, error)) {
+	start := time.Now()
+
+	return ctx, func(resp *http.Response, err error) {
+		if err != nil {
+			c.requestDuration.WithLabelValues(route, "").Observe(time.Since(start).Seconds())
+			c.errors.WithLabelValues(errorCodeRequestFailed).Inc()
+			return
+		}
+
+		c.requestDuration.WithLabelValues(route, strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+		if resp.StatusCode >= http.StatusBadRequest {
+			c.errors.WithLabelValues(fmt.Sprintf(errorCodeHTTPStatus, resp.StatusCode)).Inc()
+		}
+	}
+}
+
+// ObserveCache counts a cache lookup. The cache hit ratio is the rate of
+// hits divided by the rate of all lookups.
+func (c *Collector) ObserveCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	c.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// ObserveShadowComparison counts a comparison of a shadow evaluation. Pass
+// it to adapter.NewShadow with adapter.OnCompare.
+func (c *Collector) ObserveShadowComparison(flag string, match bool) {
+	result := "mismatch"
+	if match {
+		result = "match"
+	}
+
+	c.shadowComparisons.WithLabelValues(flag, result).Inc()
+}
+
+// SetPendingMetricUpdates sets the number of metric updates waiting to be sent.
+func (c *Collector) SetPendingMetricUpdates(n int) {
+	c.pendingMetricUpdates.Set(float64(n))
+}
@@ -0,0 +1,84 @@
+package kickplanprom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+func TestCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/features/flag":
+			_, _ = w.Write([]byte(`{"key": "flag", "value": true, "variant": "on"}`))
+		case "/features/missing":
+			_, _ = w.Write([]byte(`{"key": "missing", "error_code": "FLAG_NOT_FOUND"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	collector := NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("failed to register collector: %v", err)
+	}
+
+	client := kickplan.NewClient(
+		kickplan.WithAdapter(adapter.NewKickplan(server.URL, "token", "", "",
+			adapter.WithRequestHook(collector.ObserveRequest),
+		)),
+		kickplan.WithHook(collector),
+	)
+
+	if _, err := client.GetBool(context.TODO(), "flag", false, nil); err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	_, _ = client.GetBool(context.TODO(), "missing", false, nil)
+	_ = client.SetMetric(context.TODO(), "metric", 1, nil)
+
+	collector.ObserveCache(true)
+	collector.ObserveCache(false)
+	collector.SetPendingMetricUpdates(3)
+	collector.ObserveShadowComparison("flag", true)
+	collector.ObserveShadowComparison("flag", false)
+
+	tests := []struct {
+		name     string
+		metric   prometheus.Collector
+		expected float64
+	}{
+		{"evaluations on", collector.evaluations.WithLabelValues("flag", "on", adapter.ReasonTargetingMatch), 1},
+		{"evaluations missing", collector.evaluations.WithLabelValues("missing", "", adapter.ReasonError), 1},
+		{"flag not found errors", collector.errors.WithLabelValues("FLAG_NOT_FOUND"), 1},
+		{"http errors", collector.errors.WithLabelValues("HTTP_500"), 1},
+		{"cache hits", collector.cacheLookups.WithLabelValues("hit"), 1},
+		{"cache misses", collector.cacheLookups.WithLabelValues("miss"), 1},
+		{"shadow matches", collector.shadowComparisons.WithLabelValues("flag", "match"), 1},
+		{"shadow mismatches", collector.shadowComparisons.WithLabelValues("flag", "mismatch"), 1},
+		{"pending metric updates", collector.pendingMetricUpdates, 3},
+	}
+
+	for _, tt := range tests {
+		if actual := testutil.ToFloat64(tt.metric); actual != tt.expected {
+			t.Fatalf("expected %s to be %v, got %v", tt.name, tt.expected, actual)
+		}
+	}
+
+	if count := testutil.CollectAndCount(collector.requestDuration); count != 2 {
+		t.Fatalf("expected 2 request duration series, got %d", count)
+	}
+
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+}
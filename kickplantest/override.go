@@ -0,0 +1,257 @@
+package kickplantest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Override makes client return value for flag until the test finishes.
+//
+// The override is layered over the adapter used by the client, so all other
+// flags keep their values. Overrides of the same flag stack: the most recent
+// one wins and removing it restores the previous one. Override is safe to
+// use from parallel subtests, but subtests that share a client also share
+// its overrides, so parallel subtests should override different flags or
+// use their own client. Override fails the test if the client is closed.
+func Override(tb testing.TB, client *kickplan.Client, flag string, value interface{}) {
+	tb.Helper()
+
+	var o *overrides
+	client.WrapAdapter(func(a adapter.Adapter) adapter.Adapter {
+		if existing, ok := a.(*overrides); ok {
+			o = existing
+			return a
+		}
+
+		o = &overrides{Adapter: a, flags: make(map[string][]*override)}
+		return o
+	})
+
+	if o == nil {
+		tb.Fatalf("kickplantest: client is closed")
+		return
+	}
+
+	v := o.push(flag, value)
+	tb.Cleanup(func() { o.remove(flag, v) })
+}
+
+// Verify that overrides implements adapter.Adapter, adapter.BulkEvaluator,
+// adapter.MetricReader, adapter.MetricRecorder and adapter.Wrapper.
+var (
+	_ adapter.Adapter        = (*overrides)(nil)
+	_ adapter.BulkEvaluator  = (*overrides)(nil)
+	_ adapter.MetricReader   = (*overrides)(nil)
+	_ adapter.MetricRecorder = (*overrides)(nil)
+	_ adapter.Wrapper        = (*overrides)(nil)
+)
+
+type override struct {
+	value interface{}
+}
+
+// overrides is an adapter that serves overridden flags and delegates
+// everything else to the wrapped adapter.
+type overrides struct {
+	adapter.Adapter
+
+	mu    sync.RWMutex
+	flags map[string][]*override
+}
+
+// Unwrap returns the wrapped adapter.
+func (o *overrides) Unwrap() adapter.Adapter {
+	return o.Adapter
+}
+
+// RecordMetric records a value of a counter, gauge or histogram with the
+// wrapped adapter.
+func (o *overrides) RecordMetric(
+	ctx context.Context,
+	metric string,
+	kind adapter.MetricKind,
+	value float64,
+	evalCtx eval.Context,
+) error {
+	return adapter.RecordMetric(ctx, o.Adapter, metric, kind, value, evalCtx)
+}
+
+// ReadMetric returns the value of a metric from the wrapped adapter.
+func (o *overrides) ReadMetric(ctx context.Context, metric string, evalCtx eval.Context) (adapter.Metric, error) {
+	return adapter.ReadMetric(ctx, o.Adapter, metric, evalCtx)
+}
+
+func (o *overrides) push(flag string, value interface{}) *override {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	v := &override{value: value}
+	o.flags[flag] = append(o.flags[flag], v)
+
+	return v
+}
+
+func (o *overrides) remove(flag string, v *override) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stack := o.flags[flag]
+	for i := range stack {
+		if stack[i] == v {
+			stack = append(stack[:i], stack[i+1:]...)
+			break
+		}
+	}
+
+	if len(stack) == 0 {
+		delete(o.flags, flag)
+		return
+	}
+
+	o.flags[flag] = stack
+}
+
+func (o *overrides) find(ctx context.Context, flag string) (interface{}, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	stack := o.flags[flag]
+	if len(stack) == 0 {
+		return nil, false
+	}
+
+	adapter.ReportDetails(ctx, adapter.Details{Reason: adapter.ReasonStatic})
+	return stack[len(stack)-1].value, true
+}
+
+// BooleanEvaluation returns the value of a boolean flag.
+func (o *overrides) BooleanEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue bool,
+	evalCtx eval.Context,
+) (bool, error) {
+	v, ok := o.find(ctx, flag)
+	if !ok {
+		return o.Adapter.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return defaultValue, typeMismatch(flag, v)
+	}
+
+	return b, nil
+}
+
+// StringEvaluation returns the value of a string flag.
+func (o *overrides) StringEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue string,
+	evalCtx eval.Context,
+) (string, error) {
+	v, ok := o.find(ctx, flag)
+	if !ok {
+		return o.Adapter.StringEvaluation(ctx, flag, defaultValue, evalCtx)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return defaultValue, typeMismatch(flag, v)
+	}
+
+	return s, nil
+}
+
+// Int64Evaluation returns the value of a int64 flag. Overrides of any
+// integer type are accepted.
+func (o *overrides) Int64Evaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue int64,
+	evalCtx eval.Context,
+) (int64, error) {
+	v, ok := o.find(ctx, flag)
+	if !ok {
+		return o.Adapter.Int64Evaluation(ctx, flag, defaultValue, evalCtx)
+	}
+
+	switch i := v.(type) {
+	case int:
+		return int64(i), nil
+	case int32:
+		return int64(i), nil
+	case int64:
+		return i, nil
+	default:
+		return defaultValue, typeMismatch(flag, v)
+	}
+}
+
+// ObjectEvaluation returns the value of a object flag.
+func (o *overrides) ObjectEvaluation(
+	ctx context.Context,
+	flag string,
+	defaultValue interface{},
+	evalCtx eval.Context,
+) (interface{}, error) {
+	v, ok := o.find(ctx, flag)
+	if !ok {
+		return o.Adapter.ObjectEvaluation(ctx, flag, defaultValue, evalCtx)
+	}
+
+	return v, nil
+}
+
+// AllEvaluations returns the evaluations of the wrapped adapter with the
+// overridden flags replaced. It fails if the wrapped adapter cannot evaluate
+// all flags at once.
+func (o *overrides) AllEvaluations(ctx context.Context, evalCtx eval.Context) ([]adapter.FlagEvaluation, error) {
+	evaluations, err := adapter.AllEvaluations(ctx, o.Adapter, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	overridden := make(map[string]bool, len(o.flags))
+	for i, e := range evaluations {
+		if stack := o.flags[e.Flag]; len(stack) > 0 {
+			evaluations[i] = overriddenEvaluation(e.Flag, stack)
+			overridden[e.Flag] = true
+		}
+	}
+
+	for flag, stack := range o.flags {
+		if !overridden[flag] {
+			evaluations = append(evaluations, overriddenEvaluation(flag, stack))
+		}
+	}
+
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Flag < evaluations[j].Flag
+	})
+
+	return evaluations, nil
+}
+
+func overriddenEvaluation(flag string, stack []*override) adapter.FlagEvaluation {
+	return adapter.FlagEvaluation{
+		Flag:    flag,
+		Value:   stack[len(stack)-1].value,
+		Details: adapter.Details{Reason: adapter.ReasonStatic},
+	}
+}
+
+func typeMismatch(flag string, v interface{}) error {
+	return fmt.Errorf("%w: flag %q is overridden with %T", adapter.ErrTypeMismatch, flag, v)
+}
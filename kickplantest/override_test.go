@@ -0,0 +1,138 @@
+package kickplantest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+func TestOverride(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	client := kickplan.NewClient(kickplan.WithAdapter(inMemory))
+
+	if err := client.SetBool(context.TODO(), "other-flag", true); err != nil {
+		t.Fatalf("failed to set flag: %v", err)
+	}
+
+	t.Run("override", func(t *testing.T) {
+		Override(t, client, "my-flag", true)
+		Override(t, client, "limit", 10)
+
+		b, err := client.GetBool(context.TODO(), "my-flag", false, nil)
+		if err != nil {
+			t.Fatalf("failed to get flag: %v", err)
+		}
+
+		if b != true {
+			t.Fatalf("expected overridden flag to be true")
+		}
+
+		i, err := client.GetInt64(context.TODO(), "limit", 0, nil)
+		if err != nil {
+			t.Fatalf("failed to get flag: %v", err)
+		}
+
+		if i != 10 {
+			t.Fatalf("expected overridden flag to be 10, got %d", i)
+		}
+
+		b, err = client.GetBool(context.TODO(), "other-flag", false, nil)
+		if err != nil {
+			t.Fatalf("failed to get flag: %v", err)
+		}
+
+		if b != true {
+			t.Fatalf("expected other flag to keep its value")
+		}
+
+		if err := client.IncMetric(context.TODO(), "seats", 1, nil); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+
+		m, err := client.GetMetric(context.TODO(), "seats", nil)
+		if err != nil || m.Value != 1 {
+			t.Fatalf("expected metrics to reach the wrapped adapter, got %+v, %v", m, err)
+		}
+	})
+
+	b, err := client.GetBool(context.TODO(), "my-flag", false, nil)
+	if err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	if b != false {
+		t.Fatalf("expected override to be removed after the test")
+	}
+}
+
+func TestOverrideParallel(t *testing.T) {
+	client := kickplan.NewClient(kickplan.WithAdapter(adapter.NewInMemory()))
+
+	for _, flag := range []string{"a", "b", "c", "d"} {
+		t.Run(flag, func(t *testing.T) {
+			t.Parallel()
+
+			Override(t, client, flag, flag)
+
+			s, err := client.GetString(context.TODO(), flag, "", nil)
+			if err != nil {
+				t.Fatalf("failed to get flag: %v", err)
+			}
+
+			if s != flag {
+				t.Fatalf("expected overridden flag to be %s, got %s", flag, s)
+			}
+		})
+	}
+}
+
+func TestOverrideStack(t *testing.T) {
+	client := kickplan.NewClient(kickplan.WithAdapter(adapter.NewInMemory()))
+	Override(t, client, "my-flag", "outer")
+
+	t.Run("inner", func(t *testing.T) {
+		Override(t, client, "my-flag", "inner")
+
+		if s, _ := client.GetString(context.TODO(), "my-flag", "", nil); s != "inner" {
+			t.Fatalf("expected overridden flag to be inner, got %s", s)
+		}
+	})
+
+	if s, _ := client.GetString(context.TODO(), "my-flag", "", nil); s != "outer" {
+		t.Fatalf("expected overridden flag to be outer, got %s", s)
+	}
+}
+
+// fatalTB records the failure of a test instead of failing it.
+type fatalTB struct {
+	testing.TB
+	failure string
+}
+
+func (tb *fatalTB) Fatalf(format string, args ...interface{}) {
+	tb.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestOverrideClosedClient(t *testing.T) {
+	client := kickplan.NewClient(kickplan.WithAdapter(adapter.NewInMemory()))
+	if err := client.Close(context.TODO()); err != nil {
+		t.Fatalf("failed to close client: %v", err)
+	}
+
+	tb := &fatalTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Override(tb, client, "my-flag", true)
+	}()
+	<-done
+
+	if tb.failure != "kickplantest: client is closed" {
+		t.Fatalf("expected Override to fail for a closed client, got %q", tb.failure)
+	}
+}
@@ -0,0 +1,409 @@
+// Package kickplantest provides utilities for testing code that uses the Kickplan client
+package kickplantest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Token is the access token accepted by the fake server.
+const Token = "kickplantest"
+
+// Flag is a flag served by the fake server.
+type Flag struct {
+	Value     interface{}
+	Variant   string
+	Reason    string
+	ErrorCode string
+}
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// MetricUpdate is a metric update received by the fake server.
+type MetricUpdate struct {
+	Metric         string
+	Operation      string
+	Context        eval.Context
+	Value          int64
+	IdempotencyKey string
+	OccurredAt     time.Time
+	Unit           string
+	Attributes     map[string]interface{}
+}
+
+// MetricRecord is a counter, gauge or histogram value received by the fake server.
+type MetricRecord struct {
+	Metric         string
+	Kind           adapter.MetricKind
+	Context        eval.Context
+	Value          float64
+	IdempotencyKey string
+	OccurredAt     time.Time
+	Unit           string
+	Attributes     map[string]interface{}
+}
+
+// Server is a fake Kickplan API server.
+//
+// It serves the flags programmed with SetFlag and SetFlagError, answers
+// unknown flags with FLAG_NOT_FOUND and records all requests and metric
+// updates it receives. Metric updates are applied to the metric values
+// served by the metric value endpoint, except for updates with an
+// idempotency key that was already applied, which are only recorded.
+type Server struct {
+	*httptest.Server
+
+	tb              testing.TB
+	mu              sync.Mutex
+	flags           map[string]Flag
+	metrics         map[string]adapter.Metric
+	requests        []Request
+	updates         []MetricUpdate
+	records         []MetricRecord
+	idempotencyKeys map[string]struct{}
+}
+
+// NewServer starts a new fake Kickplan API server that is closed when the
+// test finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{
+		tb:              tb,
+		flags:           make(map[string]Flag),
+		metrics:         make(map[string]adapter.Metric),
+		idempotencyKeys: make(map[string]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /features", s.handleFeatures)
+	mux.HandleFunc("POST /features/{flag}", s.handleFeature)
+	mux.HandleFunc("POST /metrics/{metric}/{operation}", s.handleMetric)
+
+	s.Server = httptest.NewServer(s.record(mux))
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// Adapter returns a Kickplan adapter that sends requests to the server.
+func (s *Server) Adapter(opt ...adapter.KickplanOption) *adapter.Kickplan {
+	return adapter.NewKickplan(s.URL, Token, "", "", opt...)
+}
+
+// Client returns a Kickplan client that sends requests to the server.
+func (s *Server) Client(opt ...kickplan.Option) *kickplan.Client {
+	return kickplan.NewClient(append([]kickplan.Option{kickplan.WithAdapter(s.Adapter())}, opt...)...)
+}
+
+// SetFlag sets the value of a flag.
+func (s *Server) SetFlag(flag string, value interface{}) {
+	s.SetFlagDetails(flag, Flag{Value: value})
+}
+
+// SetFlagError makes the server answer evaluations of a flag with an error code.
+func (s *Server) SetFlagError(flag string, errorCode string) {
+	s.SetFlagDetails(flag, Flag{ErrorCode: errorCode})
+}
+
+// SetFlagDetails sets the value, variant, reason and error code of a flag.
+func (s *Server) SetFlagDetails(flag string, f Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flags[flag] = f
+}
+
+// DeleteFlag removes a flag.
+func (s *Server) DeleteFlag(flag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.flags, flag)
+}
+
+// SetMetric sets the value of a metric for an evaluation context.
+func (s *Server) SetMetric(metric string, evalCtx eval.Context, value int64) {
+	s.tb.Helper()
+
+	key, err := adapter.MetricKey(metric, evalCtx)
+	if err != nil {
+		s.tb.Fatalf("failed to set metric %q: %v", metric, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics[key] = adapter.Metric{Value: value, UpdatedAt: time.Now()}
+}
+
+// Metric returns the value of a metric for an evaluation context.
+func (s *Server) Metric(metric string, evalCtx eval.Context) (adapter.Metric, bool) {
+	s.tb.Helper()
+
+	key, err := adapter.MetricKey(metric, evalCtx)
+	if err != nil {
+		s.tb.Fatalf("failed to get metric %q: %v", metric, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.metrics[key]
+	return m, ok
+}
+
+// Requests returns all requests received by the server.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// MetricUpdates returns all metric updates received by the server.
+func (s *Server) MetricUpdates() []MetricUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]MetricUpdate(nil), s.updates...)
+}
+
+// MetricRecords returns all counter, gauge and histogram values received by
+// the server.
+func (s *Server) MetricRecords() []MetricRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]MetricRecord(nil), s.records...)
+}
+
+// Reset removes all flags and recorded requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flags = make(map[string]Flag)
+	s.metrics = make(map[string]adapter.Metric)
+	s.idempotencyKeys = make(map[string]struct{})
+	s.requests = nil
+	s.updates = nil
+	s.records = nil
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		s.mu.Unlock()
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	var req adapter.FeatureResolutionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	response := make([]adapter.FeatureResolutionResponse, 0, len(s.flags))
+	for key, f := range s.flags {
+		response = append(response, adapter.FeatureResolutionResponse{
+			ErrorCode: f.ErrorCode,
+			Key:       key,
+			Reason:    f.Reason,
+			Value:     f.Value,
+			Variant:   f.Variant,
+		})
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleFeature(w http.ResponseWriter, r *http.Request) {
+	var req adapter.FeatureResolutionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	key := r.PathValue("flag")
+
+	s.mu.Lock()
+	f, ok := s.flags[key]
+	s.mu.Unlock()
+
+	if !ok {
+		f = Flag{ErrorCode: "FLAG_NOT_FOUND"}
+	}
+
+	writeJSON(w, http.StatusOK, adapter.FeatureResolutionResponse{
+		ErrorCode: f.ErrorCode,
+		Key:       key,
+		Reason:    f.Reason,
+		Value:     f.Value,
+		Variant:   f.Variant,
+	})
+}
+
+func (s *Server) handleMetric(w http.ResponseWriter, r *http.Request) {
+	operation := r.PathValue("operation")
+	switch operation {
+	case "set", "increment", "decrement":
+	case "value":
+		s.handleMetricValue(w, r)
+		return
+	case "record":
+		s.handleMetricRecord(w, r)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var req adapter.MetricUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	metric := r.PathValue("metric")
+	key, err := adapter.MetricKey(metric, req.Context)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = append(s.updates, MetricUpdate{
+		Metric:         metric,
+		Operation:      operation,
+		Context:        req.Context,
+		Value:          req.Value,
+		IdempotencyKey: req.IdempotencyKey,
+		OccurredAt:     req.OccurredAt,
+		Unit:           req.Unit,
+		Attributes:     req.Attributes,
+	})
+
+	if req.IdempotencyKey != "" {
+		if _, ok := s.idempotencyKeys[req.IdempotencyKey]; ok {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		s.idempotencyKeys[req.IdempotencyKey] = struct{}{}
+	}
+
+	m := s.metrics[key]
+	switch operation {
+	case "set":
+		m.Value = req.Value
+	case "increment":
+		m.Value += req.Value
+	case "decrement":
+		m.Value -= req.Value
+	}
+	m.UpdatedAt = time.Now()
+	s.metrics[key] = m
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleMetricRecord(w http.ResponseWriter, r *http.Request) {
+	var req adapter.MetricRecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, MetricRecord{
+		Metric:         r.PathValue("metric"),
+		Kind:           req.Kind,
+		Context:        req.Context,
+		Value:          req.Value,
+		IdempotencyKey: req.IdempotencyKey,
+		OccurredAt:     req.OccurredAt,
+		Unit:           req.Unit,
+		Attributes:     req.Attributes,
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleMetricValue(w http.ResponseWriter, r *http.Request) {
+	var req adapter.MetricValueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	metric := r.PathValue("metric")
+	key, err := adapter.MetricKey(metric, req.Context)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	m, ok := s.metrics[key]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusOK, adapter.MetricValueResponse{ErrorCode: "METRIC_NOT_FOUND", Key: metric})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapter.MetricValueResponse{
+		Key:       metric,
+		Value:     m.Value,
+		UpdatedAt: m.UpdatedAt,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,157 @@
+package kickplantest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func TestServerFlags(t *testing.T) {
+	server := NewServer(t)
+	server.SetFlag("enabled", true)
+	server.SetFlagError("broken", "PARSE_ERROR")
+
+	client := server.Client()
+
+	b, err := client.GetBool(context.TODO(), "enabled", false, eval.Context{"account_id": "account"})
+	if err != nil {
+		t.Fatalf("failed to get flag: %v", err)
+	}
+
+	if b != true {
+		t.Fatalf("expected flag to be true")
+	}
+
+	_, err = client.GetBool(context.TODO(), "missing", false, nil)
+	if !errors.Is(err, adapter.ErrFlagNotFound) {
+		t.Fatalf("expected error to be ErrFlagNotFound, got %v", err)
+	}
+
+	_, err = client.GetBool(context.TODO(), "broken", false, nil)
+	if err == nil || err.Error() != "PARSE_ERROR" {
+		t.Fatalf("expected error to be PARSE_ERROR, got %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+
+	if requests[0].Path != "/features/enabled" {
+		t.Fatalf("expected request path to be /features/enabled, got %s", requests[0].Path)
+	}
+}
+
+func TestServerMetricUpdates(t *testing.T) {
+	server := NewServer(t)
+	client := server.Client()
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if err := client.SetMetric(context.TODO(), "seats", 10, evalCtx); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	if err := client.IncMetric(context.TODO(), "seats", 2, evalCtx); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	if err := client.DecMetric(context.TODO(), "seats", 1, evalCtx); err != nil {
+		t.Fatalf("failed to decrement metric: %v", err)
+	}
+
+	expected := []MetricUpdate{
+		{Metric: "seats", Operation: "set", Value: 10},
+		{Metric: "seats", Operation: "increment", Value: 2},
+		{Metric: "seats", Operation: "decrement", Value: 1},
+	}
+
+	updates := server.MetricUpdates()
+	if len(updates) != len(expected) {
+		t.Fatalf("expected %d metric updates, got %d", len(expected), len(updates))
+	}
+
+	for i, u := range updates {
+		if u.Metric != expected[i].Metric || u.Operation != expected[i].Operation || u.Value != expected[i].Value {
+			t.Fatalf("expected metric update %d to be %+v, got %+v", i, expected[i], u)
+		}
+
+		if u.Context["account_id"] != "account" {
+			t.Fatalf("expected metric update context account_id to be account, got %v", u.Context["account_id"])
+		}
+	}
+}
+
+func TestServerMetricValue(t *testing.T) {
+	server := NewServer(t)
+	client := server.Client()
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if _, err := client.GetMetric(context.TODO(), "seats", evalCtx); !errors.Is(err, adapter.ErrMetricNotFound) {
+		t.Fatalf("expected error to be ErrMetricNotFound, got %v", err)
+	}
+
+	server.SetMetric("seats", evalCtx, 10)
+	if err := client.IncMetric(context.TODO(), "seats", 2, evalCtx); err != nil {
+		t.Fatalf("failed to increment metric: %v", err)
+	}
+
+	m, err := client.GetMetric(context.TODO(), "seats", evalCtx)
+	if err != nil {
+		t.Fatalf("failed to get metric: %v", err)
+	}
+
+	if m.Value != 12 {
+		t.Fatalf("expected metric value to be 12, got %d", m.Value)
+	}
+
+	if m.UpdatedAt.IsZero() {
+		t.Fatalf("expected metric updated at to be set")
+	}
+}
+
+func TestServerIdempotencyKey(t *testing.T) {
+	server := NewServer(t)
+	client := server.Client()
+
+	for range 2 {
+		if err := client.IncMetric(context.TODO(), "seats", 1, nil, adapter.WithIdempotencyKey("key")); err != nil {
+			t.Fatalf("failed to increment metric: %v", err)
+		}
+	}
+
+	if updates := server.MetricUpdates(); len(updates) != 2 || updates[1].IdempotencyKey != "key" {
+		t.Fatalf("expected 2 metric updates with idempotency key, got %+v", updates)
+	}
+
+	m, ok := server.Metric("seats", nil)
+	if !ok || m.Value != 1 {
+		t.Fatalf("expected metric seats to be 1, got %+v", m)
+	}
+}
+
+func TestServerMetricRecords(t *testing.T) {
+	server := NewServer(t)
+	client := server.Client()
+
+	evalCtx := eval.Context{"account_id": "account"}
+	if err := client.Observe(context.TODO(), "latency", 12.5, evalCtx, adapter.WithUnit("ms")); err != nil {
+		t.Fatalf("failed to observe histogram: %v", err)
+	}
+
+	records := server.MetricRecords()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 metric record, got %d", len(records))
+	}
+
+	r := records[0]
+	if r.Metric != "latency" || r.Kind != adapter.MetricKindHistogram || r.Value != 12.5 || r.Unit != "ms" {
+		t.Fatalf("expected histogram latency of 12.5 ms, got %+v", r)
+	}
+
+	if r.IdempotencyKey == "" {
+		t.Fatalf("expected metric record to have an idempotency key")
+	}
+}
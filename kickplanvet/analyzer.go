@@ -0,0 +1,259 @@
+// Package kickplanvet provides an analyzer that finds the flags evaluated by
+// Go code with the Kickplan client or adapters.
+package kickplanvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	clientPath = "github.com/kickplan/sdk-go"
+	evalPath   = "github.com/kickplan/sdk-go/eval"
+)
+
+// Flag types reported in usages.
+const (
+	TypeBool   = "bool"
+	TypeInt64  = "int64"
+	TypeString = "string"
+	TypeObject = "object"
+)
+
+// clientMethods maps the evaluation methods of kickplan.Client to the type
+// of the flags they evaluate.
+var clientMethods = map[string]string{
+	"GetBool":          TypeBool,
+	"GetBoolDetails":   TypeBool,
+	"GetInt64":         TypeInt64,
+	"GetInt64Details":  TypeInt64,
+	"GetString":        TypeString,
+	"GetStringDetails": TypeString,
+	"GetObject":        TypeObject,
+	"GetObjectDetails": TypeObject,
+}
+
+// adapterMethods maps the evaluation methods of adapter.Adapter to the type
+// of the flags they evaluate.
+var adapterMethods = map[string]string{
+	"BooleanEvaluation": TypeBool,
+	"Int64Evaluation":   TypeInt64,
+	"StringEvaluation":  TypeString,
+	"ObjectEvaluation":  TypeObject,
+}
+
+var reportUsages bool
+
+// Analyzer finds the calls of kickplan.Client.Get* and the evaluation
+// methods of adapters. It reports flag keys that are not constants and flags
+// that are evaluated with different types or default values. With the
+// -usages flag, it also reports every usage with its key and default value.
+//
+// Packages of the SDK itself are not analyzed. Usages are exported as facts, so conflicts with usages in imported
+// packages are reported too. Conflicts between packages that do not import
+// each other are not detected.
+//
+// The result of the analyzer is the []Usage of the package.
+var Analyzer = &analysis.Analyzer{
+	Name:       "kickplanvet",
+	Doc:        "find Kickplan flag usages and report inconsistent keys, types and defaults",
+	URL:        "https://pkg.go.dev/github.com/kickplan/sdk-go/kickplanvet",
+	Requires:   []*analysis.Analyzer{inspect.Analyzer},
+	Run:        run,
+	FactTypes:  []analysis.Fact{new(usagesFact)},
+	ResultType: reflect.TypeOf([]Usage(nil)),
+}
+
+func init() {
+	Analyzer.Flags.BoolVar(&reportUsages, "usages", false, "report every flag usage")
+}
+
+// Usage is an evaluation of a flag found in the code.
+type Usage struct {
+	Flag string
+	Type string
+
+	// Default is the default value as Go source, or empty if it is not a
+	// constant.
+	Default string
+
+	// Position is the position of the call.
+	Position string
+}
+
+// usagesFact holds the usages of a package.
+type usagesFact struct {
+	Usages []Usage
+}
+
+func (*usagesFact) AFact() {}
+
+func (f *usagesFact) String() string {
+	flags := make([]string, 0, len(f.Usages))
+	for _, u := range f.Usages {
+		flags = append(flags, u.Flag)
+	}
+
+	return "usages(" + strings.Join(flags, ", ") + ")"
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// the SDK forwards flag keys between its own packages
+	if pass.Pkg.Path() == clientPath || strings.HasPrefix(pass.Pkg.Path(), clientPath+"/") {
+		return []Usage(nil), nil
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// usages of imported packages, in a stable order
+	var known []Usage
+	for _, f := range pass.AllPackageFacts() {
+		if fact, ok := f.Fact.(*usagesFact); ok && f.Package != pass.Pkg {
+			known = append(known, fact.Usages...)
+		}
+	}
+	sort.Slice(known, func(i, j int) bool {
+		return known[i].Position < known[j].Position
+	})
+
+	var usages []Usage
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+
+		typ, ok := evaluationType(pass.TypesInfo, call)
+		if !ok || len(call.Args) < 3 {
+			return
+		}
+
+		key, def := call.Args[1], call.Args[2]
+
+		tv := pass.TypesInfo.Types[key]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			pass.Reportf(key.Pos(), "flag key is not a constant")
+			return
+		}
+
+		u := Usage{
+			Flag:     constant.StringVal(tv.Value),
+			Type:     typ,
+			Default:  defaultValue(pass, def),
+			Position: pass.Fset.Position(call.Pos()).String(),
+		}
+
+		if reportUsages {
+			d := u.Default
+			if d == "" {
+				d = "non-constant"
+			}
+			pass.Reportf(call.Pos(), "flag %q (%s, default %s)", u.Flag, u.Type, d)
+		}
+
+		reportConflict(pass, call.Pos(), u, known)
+
+		usages = append(usages, u)
+		known = append(known, u)
+	})
+
+	if len(usages) > 0 {
+		pass.ExportPackageFact(&usagesFact{Usages: usages})
+	}
+
+	return usages, nil
+}
+
+// reportConflict reports a usage whose type differs from the first usage of
+// the same flag, or whose default differs from the first usage of the same
+// flag with a constant default.
+func reportConflict(pass *analysis.Pass, pos token.Pos, u Usage, known []Usage) {
+	var first *Usage
+	for i, k := range known {
+		if k.Flag != u.Flag {
+			continue
+		}
+
+		if first == nil {
+			first = &known[i]
+			if k.Type != u.Type {
+				pass.Reportf(pos, "flag %q is evaluated as %s here and as %s at %s", u.Flag, u.Type, k.Type, k.Position)
+				return
+			}
+		}
+
+		if k.Type == u.Type && k.Default != "" && u.Default != "" {
+			if k.Default != u.Default {
+				pass.Reportf(pos, "flag %q has default %s here and %s at %s", u.Flag, u.Default, k.Default, k.Position)
+			}
+			return
+		}
+	}
+}
+
+// evaluationType returns the type of the flag evaluated by call if it is an
+// evaluation method of kickplan.Client or of an adapter.
+func evaluationType(info *types.Info, call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok {
+		return "", false
+	}
+
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return "", false
+	}
+
+	if typ, ok := clientMethods[fn.Name()]; ok && isNamed(sig.Recv().Type(), clientPath, "Client") {
+		return typ, true
+	}
+
+	// adapters are recognized by the signature of their evaluation methods,
+	// so that calls through adapter.Adapter and concrete adapters are found
+	if typ, ok := adapterMethods[fn.Name()]; ok && sig.Params().Len() == 4 &&
+		isNamed(sig.Params().At(3).Type(), evalPath, "Context") {
+		return typ, true
+	}
+
+	return "", false
+}
+
+func isNamed(t types.Type, path, name string) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == path && obj.Name() == name
+}
+
+// defaultValue returns the default value of a usage as Go source if it is a
+// constant or nil.
+func defaultValue(pass *analysis.Pass, expr ast.Expr) string {
+	tv := pass.TypesInfo.Types[expr]
+	if tv.Value != nil {
+		return tv.Value.ExactString()
+	}
+
+	if tv.IsNil() {
+		return "nil"
+	}
+
+	return ""
+}
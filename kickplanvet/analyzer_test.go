@@ -0,0 +1,39 @@
+package kickplanvet
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a", "b")
+}
+
+func TestAnalyzerUsages(t *testing.T) {
+	if err := Analyzer.Flags.Set("usages", "true"); err != nil {
+		t.Fatalf("failed to set flag: %v", err)
+	}
+	defer func() { _ = Analyzer.Flags.Set("usages", "false") }()
+
+	results := analysistest.Run(t, analysistest.TestData(), Analyzer, "c")
+
+	var usages []Usage
+	for _, r := range results {
+		if r.Pass.Pkg.Path() == "c" {
+			usages = r.Result.([]Usage)
+		}
+	}
+
+	if len(usages) != 2 {
+		t.Fatalf("expected 2 usages, got %d", len(usages))
+	}
+
+	if usages[0].Flag != "beta" || usages[0].Type != TypeBool || usages[0].Default != "false" {
+		t.Fatalf("unexpected usage %+v", usages[0])
+	}
+
+	if usages[1].Default != "" {
+		t.Fatalf("expected non-constant default to be empty, got %q", usages[1].Default)
+	}
+}
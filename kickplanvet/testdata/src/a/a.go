@@ -0,0 +1,20 @@
+package a // want package:"usages\\(enabled, enabled, seats, enabled, seats, beta\\)"
+
+import (
+	"context"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+const Seats = "seats"
+
+func Check(ctx context.Context, c *kickplan.Client, a adapter.Adapter, flag string) {
+	c.GetBool(ctx, "enabled", false, nil)
+	c.GetBool(ctx, "enabled", false, nil)
+	c.GetInt64(ctx, Seats, 10, nil)
+	c.GetBool(ctx, flag, false, nil)     // want `flag key is not a constant`
+	c.GetString(ctx, "enabled", "", nil) // want `flag "enabled" is evaluated as string here and as bool at .*a.go:13:2`
+	c.GetInt64(ctx, Seats, 5, nil)       // want `flag "seats" has default 5 here and 10 at .*a.go:15:2`
+	a.BooleanEvaluation(ctx, "beta", true, nil)
+}
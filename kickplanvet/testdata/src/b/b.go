@@ -0,0 +1,16 @@
+package b // want package:"usages\\(beta, enabled\\)"
+
+import (
+	"context"
+
+	kickplan "github.com/kickplan/sdk-go"
+	"github.com/kickplan/sdk-go/adapter"
+
+	"a"
+)
+
+func Check(ctx context.Context, c *kickplan.Client, ad adapter.Adapter, def bool) {
+	a.Check(ctx, c, ad, "")
+	c.GetBool(ctx, "beta", false, nil) // want `flag "beta" has default false here and true at .*a.go:19:2`
+	c.GetBool(ctx, "enabled", def, nil)
+}
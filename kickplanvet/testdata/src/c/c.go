@@ -0,0 +1,12 @@
+package c // want package:"usages\\(beta, enabled\\)"
+
+import (
+	"context"
+
+	kickplan "github.com/kickplan/sdk-go"
+)
+
+func Check(ctx context.Context, c *kickplan.Client, def bool) {
+	c.GetBool(ctx, "beta", false, nil)  // want `flag "beta" \(bool, default false\)`
+	c.GetBool(ctx, "enabled", def, nil) // want `flag "enabled" \(bool, default non-constant\)`
+}
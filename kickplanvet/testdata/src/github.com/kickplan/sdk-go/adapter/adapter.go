@@ -0,0 +1,12 @@
+package adapter
+
+import (
+	"context"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+type Adapter interface {
+	BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx eval.Context) (bool, error)
+	StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx eval.Context) (string, error)
+}
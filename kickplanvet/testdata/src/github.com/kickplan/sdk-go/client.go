@@ -0,0 +1,21 @@
+package kickplan
+
+import (
+	"context"
+
+	"github.com/kickplan/sdk-go/eval"
+)
+
+type Client struct{}
+
+func (c *Client) GetBool(ctx context.Context, flag string, defaultValue bool, evalCtx eval.Context) (bool, error) {
+	return defaultValue, nil
+}
+
+func (c *Client) GetInt64(ctx context.Context, flag string, defaultValue int64, evalCtx eval.Context) (int64, error) {
+	return defaultValue, nil
+}
+
+func (c *Client) GetString(ctx context.Context, flag string, defaultValue string, evalCtx eval.Context) (string, error) {
+	return defaultValue, nil
+}
@@ -0,0 +1,3 @@
+package eval
+
+type Context map[string]interface{}
@@ -0,0 +1,73 @@
+package kickplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+// ErrClientClosed is returned by the methods of a client after Close.
+var ErrClientClosed = errors.New("CLIENT_CLOSED")
+
+// Init initializes the adapter of the client and the adapters it wraps that
+// implement adapter.Lifecycle, e.g. loads snapshot files and checks that the
+// Kickplan API is reachable. Call it once before the client is used.
+func (c *Client) Init(ctx context.Context) error {
+	return adapter.Init(ctx, c.Adapter())
+}
+
+// Close stops the client: new calls fail with ErrClientClosed, calls in
+// flight are waited for and the adapter is shut down, e.g. pending metric
+// updates are delivered and idle connections are closed. Both are bounded by
+// ctx; the adapter is shut down even if calls are still in flight when ctx
+// is done. Closing a closed client does nothing.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+
+	c.closed = true
+	if c.stopWatch != nil {
+		c.stopWatch()
+		c.stopWatch = nil
+	}
+	a := c.adapter
+	c.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to wait for calls in flight: %w", ctx.Err())
+	}
+
+	return errors.Join(err, adapter.Shutdown(ctx, a))
+}
+
+// call calls fn with the adapter of the client and tracks the call until it
+// returns. It returns ErrClientClosed after Close.
+func (c *Client) call(fn func(adapter.Adapter) error) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return ErrClientClosed
+	}
+
+	a := c.adapter
+	c.inflight.Add(1)
+	c.mu.RUnlock()
+
+	defer c.inflight.Done()
+
+	return fn(a)
+}
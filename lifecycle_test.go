@@ -0,0 +1,120 @@
+package kickplan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+// shutdownAdapter is an adapter that records whether it was shut down.
+type shutdownAdapter struct {
+	*adapter.InMemory
+
+	initialized bool
+	shutdown    bool
+}
+
+func (a *shutdownAdapter) Init(_ context.Context) error {
+	a.initialized = true
+	return nil
+}
+
+func (a *shutdownAdapter) Shutdown(_ context.Context) error {
+	a.shutdown = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	base := &shutdownAdapter{InMemory: adapter.NewInMemory()}
+	base.Flags["enabled"] = adapter.InMemoryFlag{Value: true}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := adapter.Intercept(func(ctx context.Context, call *adapter.Call, next func(context.Context) error) error {
+		if call.Key == "slow" {
+			close(started)
+			<-release
+		}
+
+		return next(ctx)
+	})
+
+	var details []EvaluationDetails
+	client := NewClient(
+		WithAdapter(adapter.Chain(base, blocking)),
+		WithHook(HookFunc(func(_ context.Context, d EvaluationDetails, _ error) {
+			details = append(details, d)
+		})),
+	)
+
+	if err := client.Init(context.TODO()); err != nil || !base.initialized {
+		t.Fatalf("expected adapter to be initialized, got %v", err)
+	}
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := client.GetBool(context.TODO(), "slow", false, nil)
+		slow <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+
+	if err := client.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while a call is in flight, got %v", err)
+	}
+
+	if !base.shutdown {
+		t.Fatalf("expected adapter to be shut down")
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatalf("expected call in flight to complete, got %v", err)
+	}
+
+	if v, err := client.GetBool(context.TODO(), "enabled", false, nil); !errors.Is(err, ErrClientClosed) || v {
+		t.Fatalf("expected default value and ErrClientClosed, got %v, %v", v, err)
+	}
+
+	if d := details[len(details)-1]; d.Reason != adapter.ReasonError || d.ErrorCode != "CLIENT_CLOSED" {
+		t.Fatalf("expected hooks to see CLIENT_CLOSED, got %+v", d)
+	}
+
+	if err := client.IncMetric(context.TODO(), "seats", 1, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+
+	if err := client.Close(context.TODO()); err != nil {
+		t.Fatalf("expected closing a closed client to succeed, got %v", err)
+	}
+}
+
+func TestWrapAdapterAfterClose(t *testing.T) {
+	base := adapter.NewInMemory()
+	c := NewClient(WithAdapter(base), WithStatusHandler(func(adapter.StatusEvent) {}))
+
+	if err := c.Close(context.TODO()); err != nil {
+		t.Fatalf("failed to close client: %v", err)
+	}
+
+	c.WrapAdapter(func(a adapter.Adapter) adapter.Adapter {
+		t.Fatalf("expected wrap not to be called after close")
+		return a
+	})
+
+	if c.Adapter() != base {
+		t.Fatalf("expected adapter to be unchanged")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopWatch != nil {
+		t.Fatalf("expected status not to be watched after close")
+	}
+}
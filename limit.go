@@ -0,0 +1,178 @@
+package kickplan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// ErrLimitExceeded is returned by Consume when the usage would exceed the limit.
+var ErrLimitExceeded = errors.New("LIMIT_EXCEEDED")
+
+// Unlimited is the value of a limit flag, or any negative value, that
+// grants unlimited usage of a feature.
+const Unlimited = -1
+
+// Limit describes the usage of a feature against its limit.
+type Limit struct {
+	// Allowed reports whether the feature may be used once more.
+	Allowed bool
+
+	// Limit is the value of the limit flag, or Unlimited.
+	Limit int64
+
+	// Used is the current value of the usage metric.
+	Used int64
+
+	// Remaining is how much of the limit is left, or Unlimited.
+	Remaining int64
+}
+
+// Entitlement maps a feature to the flag that holds its limit and the
+// metric that holds its usage.
+type Entitlement struct {
+	LimitFlag string
+	Metric    string
+}
+
+// WithEntitlement sets the limit flag and usage metric of a feature. By
+// default both are named after the feature.
+func WithEntitlement(feature string, e Entitlement) Option {
+	return func(c *Client) error {
+		if e.LimitFlag == "" || e.Metric == "" {
+			return fmt.Errorf("entitlement %q must have a limit flag and a metric", feature)
+		}
+
+		if c.entitlements == nil {
+			c.entitlements = make(map[string]Entitlement)
+		}
+		c.entitlements[feature] = e
+		return nil
+	}
+}
+
+// CheckLimit returns the usage of a feature against its limit. The limit is
+// the value of the numeric limit flag of the feature and the usage is the
+// value of its metric; a metric without a value counts as no usage.
+//
+// The usage is read from the adapter, so metric updates that it has not
+// applied yet, e.g. updates still pending in an adapter.Queue, are not
+// counted.
+func (c *Client) CheckLimit(
+	ctx context.Context,
+	feature string,
+	evalCtx eval.Context,
+) (Limit, error) {
+	e := c.entitlement(feature)
+
+	limit, err := c.GetInt64(ctx, e.LimitFlag, 0, evalCtx)
+	if err != nil {
+		return Limit{}, fmt.Errorf("failed to get limit of %q: %w", feature, err)
+	}
+
+	var used int64
+	m, err := c.GetMetric(ctx, e.Metric, evalCtx)
+	switch {
+	case err == nil:
+		used = m.Value
+	case !errors.Is(err, adapter.ErrMetricNotFound):
+		return Limit{}, fmt.Errorf("failed to get usage of %q: %w", feature, err)
+	}
+
+	return newLimit(limit, used), nil
+}
+
+// Consume checks that n more units of a feature fit in its limit and
+// increments its usage metric by n. If they do not fit, the metric is left
+// unchanged and ErrLimitExceeded is returned with the current usage. n must
+// be positive.
+//
+// Concurrent calls of the same client for the same feature and evaluation
+// context are serialized, so they cannot exceed the limit together. Calls
+// from other clients or processes are not coordinated, and the usage does not
+// include pending metric updates, see CheckLimit.
+func (c *Client) Consume(
+	ctx context.Context,
+	feature string,
+	n int64,
+	evalCtx eval.Context,
+) (Limit, error) {
+	if n <= 0 {
+		return Limit{}, fmt.Errorf("failed to consume %q: n must be positive, got %d", feature, n)
+	}
+
+	mu := c.consumeLock(feature, c.mergeEvalContext(ctx, evalCtx))
+	mu.Lock()
+	defer mu.Unlock()
+
+	l, err := c.CheckLimit(ctx, feature, evalCtx)
+	if err != nil {
+		return Limit{}, err
+	}
+
+	// compare with the remaining units, so that large n cannot overflow
+	if l.Limit >= 0 && n > l.Remaining {
+		return l, ErrLimitExceeded
+	}
+
+	if err := c.IncMetric(ctx, c.entitlement(feature).Metric, n, evalCtx); err != nil {
+		return l, fmt.Errorf("failed to update usage of %q: %w", feature, err)
+	}
+
+	return newLimit(l.Limit, l.Used+n), nil
+}
+
+func (c *Client) entitlement(feature string) Entitlement {
+	if e, ok := c.entitlements[feature]; ok {
+		return e
+	}
+
+	return Entitlement{LimitFlag: feature, Metric: feature}
+}
+
+// consumeLock returns the lock that serializes Consume calls for a feature
+// and evaluation context. Locks are striped to bound memory usage.
+func (c *Client) consumeLock(feature string, evalCtx eval.Context) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(feature))
+	if b, err := json.Marshal(evalCtx); err == nil {
+		_, _ = h.Write(b)
+	}
+
+	return &c.consumeLocks[h.Sum32()%uint32(len(c.consumeLocks))]
+}
+
+func newLimit(limit, used int64) Limit {
+	if limit < 0 {
+		return Limit{
+			Allowed:   true,
+			Limit:     Unlimited,
+			Used:      used,
+			Remaining: Unlimited,
+		}
+	}
+
+	return Limit{
+		Allowed:   used < limit,
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining(limit, used),
+	}
+}
+
+// remaining returns how much of a non-negative limit is left. A negative
+// usage, e.g. after more decrements than increments, cannot make it overflow.
+func remaining(limit, used int64) int64 {
+	if used < 0 && limit > math.MaxInt64+used {
+		return math.MaxInt64
+	}
+
+	return max(limit-used, 0)
+}
@@ -0,0 +1,155 @@
+package kickplan
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+func TestCheckLimit(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seats"] = adapter.InMemoryFlag{Value: int64(5)}
+	client := NewClient(WithAdapter(inMemory))
+
+	evalCtx := eval.Context{"account_id": "account"}
+
+	l, err := client.CheckLimit(context.TODO(), "seats", evalCtx)
+	if err != nil {
+		t.Fatalf("failed to check limit: %v", err)
+	}
+
+	if l != (Limit{Allowed: true, Limit: 5, Used: 0, Remaining: 5}) {
+		t.Fatalf("expected limit to be unused, got %+v", l)
+	}
+
+	if err := client.SetMetric(context.TODO(), "seats", 5, evalCtx); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	l, err = client.CheckLimit(context.TODO(), "seats", evalCtx)
+	if err != nil {
+		t.Fatalf("failed to check limit: %v", err)
+	}
+
+	if l != (Limit{Allowed: false, Limit: 5, Used: 5, Remaining: 0}) {
+		t.Fatalf("expected limit to be used up, got %+v", l)
+	}
+}
+
+func TestCheckLimitUnlimited(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seat-limit"] = adapter.InMemoryFlag{Value: int64(Unlimited)}
+	client := NewClient(
+		WithAdapter(inMemory),
+		WithEntitlement("seats", Entitlement{LimitFlag: "seat-limit", Metric: "seat-count"}),
+	)
+
+	if err := client.SetMetric(context.TODO(), "seat-count", 1000, nil); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	l, err := client.CheckLimit(context.TODO(), "seats", nil)
+	if err != nil {
+		t.Fatalf("failed to check limit: %v", err)
+	}
+
+	if l != (Limit{Allowed: true, Limit: Unlimited, Used: 1000, Remaining: Unlimited}) {
+		t.Fatalf("expected limit to be unlimited, got %+v", l)
+	}
+}
+
+func TestCheckLimitNegativeUsage(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seats"] = adapter.InMemoryFlag{Value: int64(5)}
+	client := NewClient(WithAdapter(inMemory))
+
+	if err := client.SetMetric(context.TODO(), "seats", math.MinInt64+1, nil); err != nil {
+		t.Fatalf("failed to set metric: %v", err)
+	}
+
+	l, err := client.CheckLimit(context.TODO(), "seats", nil)
+	if err != nil {
+		t.Fatalf("failed to check limit: %v", err)
+	}
+
+	if !l.Allowed || l.Remaining != math.MaxInt64 {
+		t.Fatalf("expected remaining to be capped at math.MaxInt64, got %+v", l)
+	}
+
+	if _, err := client.Consume(context.TODO(), "seats", 1, nil); err != nil {
+		t.Fatalf("failed to consume: %v", err)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seats"] = adapter.InMemoryFlag{Value: int64(3)}
+	client := NewClient(WithAdapter(inMemory))
+
+	evalCtx := eval.Context{"account_id": "account"}
+
+	l, err := client.Consume(context.TODO(), "seats", 2, evalCtx)
+	if err != nil {
+		t.Fatalf("failed to consume: %v", err)
+	}
+
+	if l != (Limit{Allowed: true, Limit: 3, Used: 2, Remaining: 1}) {
+		t.Fatalf("expected limit after consume to be %+v, got %+v", Limit{Allowed: true, Limit: 3, Used: 2, Remaining: 1}, l)
+	}
+
+	l, err = client.Consume(context.TODO(), "seats", 2, evalCtx)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected error to be ErrLimitExceeded, got %v", err)
+	}
+
+	if l.Used != 2 {
+		t.Fatalf("expected usage to be unchanged, got %d", l.Used)
+	}
+
+	if v := inMemory.GetMetric("seats", evalCtx); v != 2 {
+		t.Fatalf("expected metric seats to be 2, got %d", v)
+	}
+}
+
+func TestConsumeInvalid(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seats"] = adapter.InMemoryFlag{Value: int64(3)}
+	client := NewClient(WithAdapter(inMemory))
+
+	for _, n := range []int64{0, -1} {
+		if _, err := client.Consume(context.TODO(), "seats", n, nil); err == nil {
+			t.Fatalf("expected error for n %d, got nil", n)
+		}
+	}
+
+	if metrics := inMemory.ListMetrics(); len(metrics) != 0 {
+		t.Fatalf("expected no metrics, got %d", len(metrics))
+	}
+}
+
+func TestConsumeConcurrent(t *testing.T) {
+	inMemory := adapter.NewInMemory()
+	inMemory.Flags["seats"] = adapter.InMemoryFlag{Value: int64(10)}
+	client := NewClient(WithAdapter(inMemory))
+
+	evalCtx := eval.Context{"account_id": "account"}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.Consume(context.TODO(), "seats", 1, evalCtx)
+		}()
+	}
+	wg.Wait()
+
+	if v := inMemory.GetMetric("seats", evalCtx); v != 10 {
+		t.Fatalf("expected metric seats to be 10, got %d", v)
+	}
+}
@@ -0,0 +1,43 @@
+package kickplan
+
+import (
+	"context"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/eval"
+)
+
+// Snapshot resolves all flags for each of the given evaluation contexts and
+// returns them as a snapshot that can be served with adapter.NewFile or
+// imported into adapter.InMemory. Without contexts, flags are resolved for
+// an empty context. Flags that fail to resolve are left out.
+func (c *Client) Snapshot(ctx context.Context, contexts ...eval.Context) (adapter.Snapshot, error) {
+	if len(contexts) == 0 {
+		contexts = []eval.Context{nil}
+	}
+
+	var s adapter.Snapshot
+	for _, evalCtx := range contexts {
+		all, err := c.GetAll(ctx, evalCtx)
+		if err != nil {
+			return adapter.Snapshot{}, err
+		}
+
+		entry := adapter.SnapshotEntry{
+			Context: evalCtx,
+			Flags:   make(map[string]adapter.SnapshotFlag, len(all)),
+		}
+
+		for _, d := range all {
+			if d.ErrorCode != "" {
+				continue
+			}
+
+			entry.Flags[d.Flag] = adapter.SnapshotFlag{Value: d.Value, Variant: d.Variant}
+		}
+
+		s.Entries = append(s.Entries, entry)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,75 @@
+package kickplan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kickplan/sdk-go/adapter"
+)
+
+// WithStatusHandler adds a function that is called with every change of the
+// status of the adapter, e.g. when it becomes ready or stale. fn must not
+// block.
+func WithStatusHandler(fn func(adapter.StatusEvent)) Option {
+	return func(c *Client) error {
+		c.statusHandlers = append(c.statusHandlers, fn)
+		return nil
+	}
+}
+
+// Status returns the status of the adapter and, if it is stale or failed,
+// the error that caused it. Adapters that do not report a status, like the
+// in-memory adapter, are always ready.
+func (c *Client) Status() (adapter.Status, error) {
+	return adapter.StatusOf(c.Adapter())
+}
+
+// WaitForReady waits until the adapter serves flags, i.e. it is ready or
+// stale, or ctx is done.
+func (c *Client) WaitForReady(ctx context.Context) error {
+	changed := make(chan struct{}, 1)
+	stop := adapter.WatchStatus(c.Adapter(), func(adapter.StatusEvent) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer stop()
+
+	for {
+		s, err := c.Status()
+		if s.Serving() {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("adapter is %s: %w: %w", s, ctx.Err(), err)
+			}
+
+			return fmt.Errorf("adapter is %s: %w", s, ctx.Err())
+		}
+	}
+}
+
+// watchStatus notifies the status handlers about changes of the status of
+// the current adapter. It must be called with c.mu held.
+func (c *Client) watchStatus() {
+	if c.stopWatch != nil {
+		c.stopWatch()
+		c.stopWatch = nil
+	}
+
+	if len(c.statusHandlers) == 0 {
+		return
+	}
+
+	handlers := c.statusHandlers
+	c.stopWatch = adapter.WatchStatus(c.adapter, func(e adapter.StatusEvent) {
+		for _, fn := range handlers {
+			fn(e)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package kickplan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kickplan/sdk-go/adapter"
+	"github.com/kickplan/sdk-go/internal/testutil"
+)
+
+func TestWaitForReady(t *testing.T) {
+	loading := testutil.NewLoadingAdapter()
+
+	events := make(chan adapter.StatusEvent, 1)
+	client := NewClient(
+		WithAdapter(loading),
+		WithStatusHandler(func(e adapter.StatusEvent) { events <- e }),
+	)
+
+	if s, _ := client.Status(); s != adapter.StatusNotReady {
+		t.Fatalf("expected status not_ready, got %s", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+
+	if err := client.WaitForReady(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	go loading.SetStatus(adapter.StatusReady, nil)
+
+	if err := client.WaitForReady(context.TODO()); err != nil {
+		t.Fatalf("expected client to become ready, got %v", err)
+	}
+
+	if e := <-events; e.Status != adapter.StatusReady {
+		t.Fatalf("expected ready event, got %+v", e)
+	}
+}
+
+func TestStatusHandlerAfterWrapAdapter(t *testing.T) {
+	loading := testutil.NewLoadingAdapter()
+
+	var events []adapter.StatusEvent
+	client := NewClient(
+		WithAdapter(adapter.NewInMemory()),
+		WithStatusHandler(func(e adapter.StatusEvent) { events = append(events, e) }),
+	)
+
+	client.WrapAdapter(func(adapter.Adapter) adapter.Adapter { return loading })
+	loading.SetStatus(adapter.StatusStale, errors.New("refresh failed"))
+
+	if len(events) != 1 || events[0].Status != adapter.StatusStale || events[0].Err == nil {
+		t.Fatalf("expected stale event with an error, got %+v", events)
+	}
+}